@@ -0,0 +1,185 @@
+package feta
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+)
+
+// exprKey is the key of the dicts standing for expressions in archives.
+const exprKey = "$expr"
+
+// An archiveEntry holds the raw metadata of an object as a JSON object, or
+// null without metadata, and its stat info. Values that aren't plain data,
+// like formulas, references and dates, are written as {"$expr": source}.
+type archiveEntry struct {
+	Meta    map[string]interface{}
+	IsDir   bool   `json:",omitempty"`
+	Size    *int64 `json:",omitempty"`
+	ModTime string `json:",omitempty"`
+}
+
+// An archive holds the entries of the exported objects by feta path.
+type archive struct {
+	Time    string
+	Objects map[string]archiveEntry
+}
+
+// toJSON converts a raw value to its JSON form in archives.
+func toJSON(value fExpr) interface{} {
+	switch v := value.(type) {
+	case fNone:
+		return nil
+	case fBool:
+		return bool(v)
+	case fNumber:
+		return float64(v)
+	case fString:
+		return string(v)
+	case fList:
+		list := make([]interface{}, len(v))
+		for i, elm := range v {
+			list[i] = toJSON(elm)
+		}
+		return list
+	case fDict:
+		dict := make(map[string]interface{}, len(v))
+		for k, elm := range v {
+			dict[k] = toJSON(elm)
+		}
+		return dict
+	}
+	return map[string]interface{}{exprKey: inline(value)}
+}
+
+// fromJSON converts a value of an archive back to a raw value.
+func fromJSON(value interface{}) (fExpr, error) {
+	switch v := value.(type) {
+	case []interface{}:
+		list := make(fList, len(v))
+		for i, elm := range v {
+			e, err := fromJSON(elm)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = e
+		}
+		return list, nil
+	case map[string]interface{}:
+		if src, isStr := v[exprKey].(string); isStr && len(v) == 1 {
+			return parseText(&src)
+		}
+		dict := make(fDict, len(v))
+		for k, elm := range v {
+			e, err := fromJSON(elm)
+			if err != nil {
+				return nil, err
+			}
+			dict[k] = e
+		}
+		return dict, nil
+	}
+	return toExpr(value)
+}
+
+// Export writes an archive of the raw metadata and stat info of the objects
+// selected by the query, or of the whole site without a query.
+func Export(query string, workDir string) ([]byte, error) {
+	snap, err := takeSnapshot(true)
+	if err != nil {
+		return nil, err
+	}
+	selected := map[string]bool{}
+	if query != "" {
+		objs, err := selectObjects(query, workDir)
+		if err != nil {
+			return nil, err
+		}
+		for _, o := range objs {
+			selected[o.fetaPath()] = true
+		}
+	}
+	arch := &archive{Time: snap.Time, Objects: map[string]archiveEntry{}}
+	for p, entry := range snap.Objects {
+		if query != "" && !selected[p] {
+			continue
+		}
+		meta, err := entry.meta(p)
+		if err != nil {
+			return nil, err
+		}
+		arEntry := archiveEntry{IsDir: entry.IsDir, Size: entry.Size, ModTime: entry.ModTime}
+		if entry.Meta != nil {
+			arEntry.Meta = toJSON(meta).(map[string]interface{})
+		}
+		arch.Objects[p] = arEntry
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if !Flags.UglyJSON {
+		enc.SetIndent("", "  ")
+	}
+	if err := enc.Encode(arch); err != nil {
+		return nil, fmt.Errorf("Couldn't encode archive: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// importArchive restores the sidecars recorded in an archive onto the objects
+// at the same paths. Entries without a matching object, or recorded as a
+// directory where there is a file or the other way around, are reported and
+// skipped, as are the entries of objects that had no metadata.
+func importArchive(path string, dryRun bool) ([]byte, error) {
+	js, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Couldn't read archive: %v", err)
+	}
+	arch := &archive{}
+	if err := json.Unmarshal(js, arch); err != nil {
+		return nil, fmt.Errorf("Couldn't decode archive: %v", err)
+	}
+	paths := fDict{}
+	for p := range arch.Objects {
+		paths[p] = fNone{}
+	}
+	objs := []*object{}
+	metas := map[*object]fDict{}
+	unmatched := fList{}
+	for _, p := range sortedKeys(paths) {
+		entry := arch.Objects[p]
+		if entry.Meta == nil {
+			continue
+		}
+		o, err := site.lookup(p)
+		if err != nil {
+			unmatched = append(unmatched, fDict{"Path": fString(p), "Reason": fString("missing")})
+			continue
+		}
+		if o.dirEntry.IsDir() != entry.IsDir {
+			unmatched = append(unmatched, fDict{"Path": fString(p), "Reason": fString("type mismatch")})
+			continue
+		}
+		meta, err := fromJSON(entry.Meta)
+		if err != nil {
+			return nil, fmt.Errorf("Couldn't read metadata of %s: %v", p, err)
+		}
+		dict, isDict := meta.(fDict)
+		if !isDict {
+			return nil, fmt.Errorf("Metadata of %s isn't a dict", p)
+		}
+		objs = append(objs, o)
+		metas[o] = dict
+	}
+	change := func(o *object, meta fDict) error {
+		for k := range meta {
+			delete(meta, k)
+		}
+		for k, v := range metas[o] {
+			meta[k] = v
+		}
+		return nil
+	}
+	return applyImport(objs, change, unmatched, dryRun)
+}
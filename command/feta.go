@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strconv"
 	"strings"
 
 	"github.com/gadfly16/feta"
@@ -17,10 +18,14 @@ func defineFlags(homeDir string) {
 		flag.BoolVar(&feta.Flags.SysAbs, "a", false, "System absolute output")
 		flag.BoolVar(&feta.Flags.UglyJSON, "u", false, "Ugly JSON output")
 		flag.BoolVar(&feta.Flags.RawOut, "r", false, "Raw output")
+		flag.Var(&feta.Flags.Vars, "var", "Query variable as name=value, can be repeated")
 	}
 }
 
-var out io.Writer = os.Stdout
+var (
+	out  io.Writer = os.Stdout
+	exit           = os.Exit
+)
 
 func main() {
 	homeDir, err := os.UserHomeDir()
@@ -52,6 +57,164 @@ func main() {
 			feta.Fatal(err)
 		}
 		fmt.Fprint(out, string(res))
+	case "set":
+		res, err := feta.Set(flag.Arg(1), flag.Arg(2), flag.Arg(3), wd)
+		if err != nil {
+			feta.Fatal(err)
+		}
+		fmt.Fprint(out, string(res))
+	case "validate":
+		query := flag.Arg(1)
+		if query == "" {
+			query = "**/"
+		}
+		res, valid, err := feta.Validate(query, wd)
+		if err != nil {
+			feta.Fatal(err)
+		}
+		fmt.Fprint(out, string(res))
+		if !valid {
+			exit(1)
+		}
+	case "fmt":
+		fmtFlags := flag.NewFlagSet("fmt", flag.ExitOnError)
+		check := fmtFlags.Bool("check", false, "Only report unformatted metadata")
+		fmtFlags.Parse(flag.Args()[1:])
+		query := fmtFlags.Arg(0)
+		if query == "" {
+			query = "**/"
+		}
+		res, formatted, err := feta.Format(query, *check, wd)
+		if err != nil {
+			feta.Fatal(err)
+		}
+		fmt.Fprint(out, string(res))
+		if *check && !formatted {
+			exit(1)
+		}
+	case "id":
+		res, err := feta.ID(flag.Arg(1), wd)
+		if err != nil {
+			feta.Fatal(err)
+		}
+		fmt.Fprint(out, string(res))
+	case "resolve":
+		res, err := feta.Resolve(flag.Arg(1))
+		if err != nil {
+			feta.Fatal(err)
+		}
+		fmt.Fprint(out, string(res))
+	case "ids":
+		res, err := feta.DuplicateIDs()
+		if err != nil {
+			feta.Fatal(err)
+		}
+		fmt.Fprint(out, string(res))
+	case "tag":
+		var res []byte
+		if flag.NArg() < 3 {
+			feta.Fatal("Usage: feta tag add|rm|ls <query> [tags...]")
+		}
+		switch flag.Arg(1) {
+		case "add":
+			res, err = feta.TagAdd(flag.Arg(2), flag.Args()[3:], wd)
+		case "rm":
+			res, err = feta.TagRemove(flag.Arg(2), flag.Args()[3:], wd)
+		case "ls":
+			res, err = feta.TagList(flag.Arg(2), wd)
+		default:
+			feta.Fatal("Unknown tag command: " + flag.Arg(1))
+		}
+		if err != nil {
+			feta.Fatal(err)
+		}
+		fmt.Fprint(out, string(res))
+	case "tags":
+		res, err := feta.Tags()
+		if err != nil {
+			feta.Fatal(err)
+		}
+		fmt.Fprint(out, string(res))
+	case "log":
+		res, err := feta.History(flag.Arg(1), wd)
+		if err != nil {
+			feta.Fatal(err)
+		}
+		fmt.Fprint(out, string(res))
+	case "undo":
+		change, err := strconv.Atoi(flag.Arg(1))
+		if err != nil {
+			feta.Fatal("Usage: feta undo <change>")
+		}
+		res, err := feta.Undo(change)
+		if err != nil {
+			feta.Fatal(err)
+		}
+		fmt.Fprint(out, string(res))
+	case "snapshot":
+		var res []byte
+		switch flag.Arg(1) {
+		case "create":
+			snapshotFlags := flag.NewFlagSet("snapshot", flag.ExitOnError)
+			withStat := snapshotFlags.Bool("stat", false, "Include stat info of objects")
+			if flag.NArg() > 3 {
+				snapshotFlags.Parse(flag.Args()[3:])
+			}
+			res, err = feta.CreateSnapshot(flag.Arg(2), *withStat)
+		case "ls":
+			res, err = feta.Snapshots()
+		default:
+			feta.Fatal("Usage: feta snapshot create <name> [-stat] | feta snapshot ls")
+		}
+		if err != nil {
+			feta.Fatal(err)
+		}
+		fmt.Fprint(out, string(res))
+	case "diff":
+		if flag.NArg() < 3 {
+			feta.Fatal("Usage: feta diff <snapshot> <snapshot|live> [query]")
+		}
+		res, err := feta.Diff(flag.Arg(1), flag.Arg(2), flag.Arg(3), wd)
+		if err != nil {
+			feta.Fatal(err)
+		}
+		fmt.Fprint(out, string(res))
+	case "export":
+		res, err := feta.Export(flag.Arg(1), wd)
+		if err != nil {
+			feta.Fatal(err)
+		}
+		fmt.Fprint(out, string(res))
+	case "import":
+		var opts feta.ImportOptions
+		importFlags := flag.NewFlagSet("import", flag.ExitOnError)
+		importFlags.StringVar(&opts.Format, "format", "", "Format of the file: json, yaml or csv")
+		importFlags.StringVar(&opts.Key, "key", "path", "Field of the records holding object paths, never imported")
+		importFlags.StringVar(&opts.Query, "query", "", "Query selecting the objects of each record")
+		importFlags.BoolVar(&opts.Replace, "replace", false, "Replace sidecars instead of merging")
+		importFlags.BoolVar(&opts.DryRun, "n", false, "Dry run, only report the changes")
+		importFlags.BoolVar(&opts.Archive, "archive", false, "Restore an archive written by export")
+		importFlags.Parse(flag.Args()[1:])
+		if importFlags.NArg() != 1 {
+			feta.Fatal("Usage: feta import [flags] <file>")
+		}
+		res, err := feta.Import(importFlags.Arg(0), opts, wd)
+		if err != nil {
+			feta.Fatal(err)
+		}
+		fmt.Fprint(out, string(res))
+	case "migrate":
+		migrateFlags := flag.NewFlagSet("migrate", flag.ExitOnError)
+		to := migrateFlags.String("to", "", "Storage to migrate to: sidecar or xattr")
+		migrateFlags.Parse(flag.Args()[1:])
+		if *to == "" {
+			feta.Fatal("Usage: feta migrate --to sidecar|xattr")
+		}
+		res, err := feta.Migrate(*to)
+		if err != nil {
+			feta.Fatal(err)
+		}
+		fmt.Fprint(out, string(res))
 	default:
 		feta.Fatal("Unknown command: " + flag.Arg(0))
 	}
@@ -6,8 +6,10 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/otiai10/copy"
+	bolt "go.etcd.io/bbolt"
 )
 
 type testCase struct {
@@ -16,16 +18,21 @@ type testCase struct {
 	want    string
 }
 
+var testTreeSrc string
+
 func initTest(t *testing.T) {
+	if testTreeSrc == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			t.Fatalf("Couldn't get working dir: %s", err)
+		}
+		testTreeSrc = wd + "/feta_test_tree"
+	}
 	err := os.RemoveAll("/tmp/feta_test_tree")
 	if err != nil {
 		t.Fatalf("Couldn't remove test dir: %s", err)
 	}
-	wd, err := os.Getwd()
-	if err != nil {
-		t.Fatalf("Couldn't get working dir: %s", err)
-	}
-	err = copy.Copy(wd+"/feta_test_tree", "/tmp/feta_test_tree")
+	err = copy.Copy(testTreeSrc, "/tmp/feta_test_tree")
 	if err != nil {
 		t.Fatalf("Couldn't copy test dir: %s", err)
 	}
@@ -127,14 +134,1271 @@ func TestSelectors(t *testing.T) {
 			want:    `[{"Obj":"/file_a","Result":"Alice"}]`,
 		},
 	}
+	runTests(t, tests)
+}
+
+func run(command string) string {
+	os.Args = toArgs(command)
+	out = bytes.NewBuffer(nil)
+	main()
+	return toString(out)
+}
+
+func runTests(t *testing.T, tests []testCase) {
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			os.Args = toArgs(tc.command)
-			out = bytes.NewBuffer(nil)
-			main()
-			if got := toString(out); got != tc.want+"\n" {
+			if got := run(tc.command); got != tc.want+"\n" {
 				t.Errorf("Want: %s  Got: %s", tc.want, got)
 			}
 		})
 	}
 }
+
+func TestExpressions(t *testing.T) {
+	initTest(t)
+	tests := []testCase{
+		{
+			name:    "Optional chaining on non-dict",
+			command: `get |data?.subdata_a?.render`,
+			want:    `none`,
+		},
+		{
+			name:    "Optional chaining short-circuits the rest",
+			command: `get |[{b:none}?.b.c,{b:none}?.b[0],{b:{}}?.b.c.d,data?.render.status]`,
+			want:    `[none,none,none,none]`,
+		},
+		{
+			name:    "Access on non-dict",
+			command: `get |data.subdata_a.render`,
+			want:    `error{"Trying to access name in non-object type."}`,
+		},
+		{
+			name:    "Null coalescing",
+			command: `get |data.render??"default"`,
+			want:    `"default"`,
+		},
+		{
+			name:    "Null coalescing existing",
+			command: `get |data.subdata_b??"default"`,
+			want:    `"thing"`,
+		},
+		{
+			name:    "Conditional",
+			command: `get |data.subdata_a>10?"big":"small"`,
+			want:    `"big"`,
+		},
+		{
+			name:    "Nested conditional",
+			command: `get |data.subdata_a<10?"small":data.subdata_a<20?"medium":"big"`,
+			want:    `"medium"`,
+		},
+		{
+			name:    "Defensive filter",
+			command: `get **/(?data?.render?.status=="done")`,
+			want:    `[]`,
+		},
+		{
+			name:    "In list",
+			command: `get |2in(data.subdata_c)`,
+			want:    `true`,
+		},
+		{
+			name:    "Not in list",
+			command: "get |2not\tin(data.subdata_c)",
+			want:    `false`,
+		},
+		{
+			name:    "In dict keys",
+			command: `get |"subdata_b"in(data)`,
+			want:    `true`,
+		},
+		{
+			name:    "Substring",
+			command: `get |"hin"in(data.subdata_b)`,
+			want:    `true`,
+		},
+		{
+			name:    "Filter by regex",
+			command: `get **/(?User=~"^B")`,
+			want:    `[` + "`/dir_a/file_b`" + `]`,
+		},
+		{
+			name:    "Regex not matching",
+			command: `get |data.subdata_b!~"^th"`,
+			want:    `false`,
+		},
+		{
+			name:    "Starts with",
+			command: `get |startswith(data.subdata_b,"th")&&endswith(data.subdata_b,"ing")`,
+			want:    `true`,
+		},
+		{
+			name:    "Short-circuit and",
+			command: `get |false&&data.subdata_a.render`,
+			want:    `false`,
+		},
+		{
+			name:    "And returns deciding operand",
+			command: `get |data&&data.subdata_b`,
+			want:    `"thing"`,
+		},
+		{
+			name:    "Or returns deciding operand",
+			command: `get |data.subdata_a||data.subdata_a.render`,
+			want:    `12`,
+		},
+		{
+			name:    "Or falls through",
+			command: `get |data.render||"fallback"`,
+			want:    `"fallback"`,
+		},
+		{
+			name:    "Not propagates errors",
+			command: `get |!data.subdata_a.render`,
+			want:    `error{"Trying to access name in non-object type."}`,
+		},
+		{
+			name:    "Short-circuit filter",
+			command: `get **/(?User&&User!="Bob")`,
+			want:    `[` + "`/file_a`" + `]`,
+		},
+		{
+			name:    "Modulo and integer division",
+			command: `get |[7%3,-7%3,7//2,-7//2]`,
+			want:    `[1,2,3,-4]`,
+		},
+		{
+			name:    "Exponent precedence",
+			command: `get |[2**3**2,-2**2,2*3**2]`,
+			want:    `[512,-4,18]`,
+		},
+		{
+			name:    "Unary minus",
+			command: `get |-data.subdata_a+-(1+2)`,
+			want:    `-15`,
+		},
+		{
+			name:    "Division by zero",
+			command: `get |data.subdata_a/(data.subdata_c[0]-1)`,
+			want:    `error{"Division by zero."}`,
+		},
+		{
+			name:    "String repetition",
+			command: `get |"ab"*3`,
+			want:    `"ababab"`,
+		},
+		{
+			name:    "String repetition too long",
+			command: `get |"ab"*1e18`,
+			want:    `error{"Repetition is too long."}`,
+		},
+		{
+			name:    "List repetition too long",
+			command: `get |[1]*1e18`,
+			want:    `error{"Repetition is too long."}`,
+		},
+		{
+			name:    "List concatenation",
+			command: `get |data.subdata_c+[4]`,
+			want:    `[1,2,3,4]`,
+		},
+		{
+			name:    "Negative index",
+			command: `get |data.subdata_c[-1]`,
+			want:    `3`,
+		},
+		{
+			name:    "List slice",
+			command: `get |data.subdata_c[1:]+data.subdata_c[::-1]`,
+			want:    `[2,3,3,2,1]`,
+		},
+		{
+			name:    "String index and slice",
+			command: `get |data.subdata_b[0]+data.subdata_b[-3:]`,
+			want:    `"ting"`,
+		},
+		{
+			name:    "Dict keys",
+			command: `get |keys(natsort)[-1]`,
+			want:    `"sh_2123"`,
+		},
+		{
+			name:    "Dict items",
+			command: `get |items(expDict)`,
+			want:    `[["a",11],["b",4.8]]`,
+		},
+		{
+			name:    "Map with lambda",
+			command: `get |map(data.subdata_c,v->v*data.subdata_a)`,
+			want:    `[12,24,36]`,
+		},
+		{
+			name:    "Filter with lambda",
+			command: `get |filter(data.subdata_c,v->v>1)`,
+			want:    `[2,3]`,
+		},
+		{
+			name:    "Reduce",
+			command: `get |reduce(data.subdata_c,(acc,v)->acc+v,10)`,
+			want:    `16`,
+		},
+		{
+			name:    "Any and all",
+			command: `get |[any(data.subdata_c,v->v>2),all(data.subdata_c,v->v>2)]`,
+			want:    `[true,false]`,
+		},
+		{
+			name:    "Sort by",
+			command: `get |map(sort_by(items(natsort),i->i[1]),i->i[0])`,
+			want:    `["sh_2123","sh_312","sh_40","sh_7"]`,
+		},
+		{
+			name:    "Unique",
+			command: `get |unique(data.subdata_c+[3,2,[1],[1]])`,
+			want:    `[1,2,3,[1]]`,
+		},
+		{
+			name:    "Nested lambdas",
+			command: `get |map([1,2],v->map([1,2],w->v*10+w))`,
+			want:    `[[11,12],[21,22]]`,
+		},
+		{
+			name:    "Let binding",
+			command: "get |let\tx=data.subdata_a,y=x*2\tin\t[x,y]",
+			want:    `[12,24]`,
+		},
+		{
+			name:    "Let binding with membership",
+			command: "get |let\tx=2\tin\tx\tin\tdata.subdata_c",
+			want:    `true`,
+		},
+		{
+			name:    "Dollar variable",
+			command: "get |let\tx=3\tin\t$x*$x",
+			want:    `9`,
+		},
+		{
+			name:    "Undefined variable",
+			command: `get |$nothing`,
+			want:    `error{"Undefined variable: $nothing"}`,
+		},
+		{
+			name:    "Command line variable",
+			command: `-var who=Bob get **/(?User==$who)`,
+			want:    `[` + "`/dir_a/file_b`" + `]`,
+		},
+		{
+			name:    "Command line number variable",
+			command: `-var min=2 get |filter(data.subdata_c,v->v>=number($min))`,
+			want:    `[2,3]`,
+		},
+		{
+			name:    "Command line variable doesn't shadow attribute",
+			command: `-var User=x get **/(?User)`,
+			want:    "[`/dir_a/file_b`,`/file_a`]",
+		},
+		{
+			name:    "Command line variable doesn't shadow dict",
+			command: `-var data=1 get |data.subdata_c`,
+			want:    `[1,2,3]`,
+		},
+	}
+	runTests(t, tests)
+}
+
+func TestReferences(t *testing.T) {
+	initTest(t)
+	tests := []testCase{
+		{
+			name:    "Reference value",
+			command: `get dir_a/shot_a|asset`,
+			want:    "`/dir_a/chair/`",
+		},
+		{
+			name:    "Raw reference",
+			command: `get dir_a/shot_a|@asset`,
+			want:    "`chair/`",
+		},
+		{
+			name:    "Attribute through reference",
+			command: `get dir_a/shot_a|asset.name`,
+			want:    `"Chair"`,
+		},
+		{
+			name:    "Chained references",
+			command: `get dir_a/shot_a|asset.source.User`,
+			want:    `"Bob"`,
+		},
+		{
+			name:    "Absolute reference with index",
+			command: `get dir_a/shot_a|plate["User"]`,
+			want:    `"Alice"`,
+		},
+		{
+			name:    "Reference equality",
+			command: `get dir_a/shot_a|asset==next.asset`,
+			want:    `true`,
+		},
+		{
+			name:    "Filter by referenced attribute",
+			command: `get dir_a/(?asset?.name=="Chair")`,
+			want:    "[`/dir_a/shot_a`,`/dir_a/shot_b`]",
+		},
+		{
+			name:    "Dangling reference",
+			command: `get dir_a/shot_a|missing`,
+			want:    "error{\"Dangling reference `shot_c` from /dir_a/shot_a\"}",
+		},
+		{
+			name:    "Reference cycle",
+			command: `get dir_a/shot_a|label`,
+			want:    `error{"Reference cycle detected at /dir_a/shot_a"}`,
+		},
+		{
+			name:    "Referrers",
+			command: `get dir_a/chair/|referrers()`,
+			want:    "[`/dir_a/shot_a`,`/dir_a/shot_b`]",
+		},
+		{
+			name:    "Referrers of argument",
+			command: `get dir_a/shot_b|referrers(asset.source)`,
+			want:    "[`/dir_a/chair/`]",
+		},
+		{
+			name:    "Filter referred objects",
+			command: `get **/(?referrers())`,
+			want:    "[`/dir_a/chair/`,`/dir_a/file_b`,`/dir_a/shot_a`,`/dir_a/shot_b`,`/file_a`]",
+		},
+	}
+	runTests(t, tests)
+}
+
+func TestIDs(t *testing.T) {
+	initTest(t)
+	tests := []testCase{
+		{
+			name:    "Resolve id",
+			command: `resolve 5f0c7a4e-3b1d-4c2a-9e8f-1a2b3c4d5e6f`,
+			want:    "`/dir_a/chair/`",
+		},
+		{
+			name:    "Select by id",
+			command: `get #5f0c7a4e-3b1d-4c2a-9e8f-1a2b3c4d5e6f|source.User`,
+			want:    `"Bob"`,
+		},
+		{
+			name:    "Existing id",
+			command: `id dir_a/chair`,
+			want:    `"5f0c7a4e-3b1d-4c2a-9e8f-1a2b3c4d5e6f"`,
+		},
+	}
+	runTests(t, tests)
+
+	id := run("id file_a")
+	if got := run("id file_a"); got != id {
+		t.Errorf("Id changed from %s to %s", id, got)
+	}
+	if got := run("resolve " + strings.Trim(id, "\"\n")); got != "`/file_a`\n" {
+		t.Errorf("Resolved new id to: %s", got)
+	}
+	if got := run("get file_a|User"); got != `"Alice"`+"\n" {
+		t.Errorf("Assigning id changed metadata: %s", got)
+	}
+	if got := run("log file_a"); !strings.Contains(got, `New: `+strings.TrimSpace(id)+`,Obj: "/file_a",Old: none,Path: "uuid"`) {
+		t.Errorf("Assigning id wasn't logged: %s", got)
+	}
+
+	err := copy.Copy("/tmp/feta_test_tree/dir_a/chair/.feta/_", "/tmp/feta_test_tree/dir_a/.feta/shot_b._")
+	if err != nil {
+		t.Fatalf("Couldn't copy sidecar: %s", err)
+	}
+	want := "{\"5f0c7a4e-3b1d-4c2a-9e8f-1a2b3c4d5e6f\": [`/dir_a/chair/`,`/dir_a/shot_b`]}\n"
+	if got := run("ids"); got != want {
+		t.Errorf("Want: %s  Got: %s", want, got)
+	}
+}
+
+func TestSchemas(t *testing.T) {
+	initTest(t)
+	exitCode := 0
+	exit = func(code int) { exitCode = code }
+	defer func() { exit = os.Exit }()
+	tests := []testCase{
+		{
+			name:    "Validate",
+			command: `validate dir_a/chair/*`,
+			want:    "[{Obj: `/dir_a/chair/seat`,Violations: [\"Missing required attribute 'count'\",\"Attribute 'material' has value \\\"steel\\\" not in [\\\"oak\\\",\\\"pine\\\"]\",\"Unknown attribute 'Usr'\"]}]",
+		},
+		{
+			name:    "Set valid value",
+			command: `set dir_a/chair/leg material "pine"`,
+			want:    "[`/dir_a/chair/leg`]",
+		},
+		{
+			name:    "Read set value",
+			command: `get dir_a/chair/leg|material`,
+			want:    `"pine"`,
+		},
+		{
+			name:    "Set nested expression",
+			command: `set file_a info.size obj.size*2`,
+			want:    "[`/file_a`]",
+		},
+		{
+			name:    "Read raw nested expression",
+			command: `get file_a|@info.size`,
+			want:    `obj.size*2`,
+		},
+		{
+			name:    "Read nested expression",
+			command: `get file_a|info.size`,
+			want:    `68`,
+		},
+		{
+			name:    "Set on invalid object",
+			command: `set dir_a/chair/seat count 1`,
+			want:    "[`/dir_a/chair/seat`]",
+		},
+	}
+	runTests(t, tests)
+	if exitCode != 1 {
+		t.Errorf("Validate exited with %d instead of 1", exitCode)
+	}
+}
+
+func TestDefaults(t *testing.T) {
+	initTest(t)
+	tests := []testCase{
+		{
+			name:    "Attribute default",
+			command: `get dir_a/chair/leg|material`,
+			want:    `"oak"`,
+		},
+		{
+			name:    "Template value",
+			command: `get dir_a/chair/leg|finish`,
+			want:    `"matte"`,
+		},
+		{
+			name:    "Stored value wins",
+			command: `get dir_a/chair/seat|material`,
+			want:    `"steel"`,
+		},
+		{
+			name:    "Defaulted keys",
+			command: `get dir_a/chair/leg|defaulted`,
+			want:    `["finish","material"]`,
+		},
+		{
+			name:    "Filter on template value",
+			command: `get dir_a/chair/*(?finish=="matte")`,
+			want:    "[`/dir_a/chair/leg`]",
+		},
+		{
+			name:    "Set defaulted key",
+			command: `set dir_a/chair/leg material "pine"`,
+			want:    "[`/dir_a/chair/leg`]",
+		},
+		{
+			name:    "Defaulted keys after set",
+			command: `get dir_a/chair/leg|defaulted`,
+			want:    `["finish"]`,
+		},
+	}
+	runTests(t, tests)
+	if data, _ := os.ReadFile("/tmp/feta_test_tree/dir_a/chair/.feta/leg._"); strings.Contains(string(data), "finish") {
+		t.Errorf("Template value was stored: %s", data)
+	}
+}
+
+func TestFormulas(t *testing.T) {
+	initTest(t)
+	tests := []testCase{
+		{
+			name:    "Formula value",
+			command: `get dir_a/file_b|fullName`,
+			want:    `"Bob Smith"`,
+		},
+		{
+			name:    "Formula on procedural",
+			command: `get dir_a/file_b|isLarge`,
+			want:    `false`,
+		},
+		{
+			name:    "Raw formula",
+			command: `get dir_a/file_b|@fullName`,
+			want:    `first+" "+last`,
+		},
+		{
+			name:    "Raw formula after evaluation",
+			command: `get dir_a/file_b|[fullName,@fullName]`,
+			want:    `["Bob Smith",first+" "+last]`,
+		},
+		{
+			name:    "Filter on formula",
+			command: `get **/(?fullName=~"Smith$")`,
+			want:    "[`/dir_a/file_b`]",
+		},
+		{
+			name:    "Set self referring subquery",
+			command: `set dir_a/file_b again (|.|again)`,
+			want:    "[`/dir_a/file_b`]",
+		},
+		{
+			name:    "Self referring subquery",
+			command: `get dir_a/file_b|again`,
+			want:    `error{"Reference cycle detected at /dir_a/file_b"}`,
+		},
+	}
+	runTests(t, tests)
+}
+
+func TestTags(t *testing.T) {
+	initTest(t)
+	tests := []testCase{
+		{
+			name:    "Add tag",
+			command: `tag add dir_a/shot_* hero`,
+			want:    "[`/dir_a/shot_a`,`/dir_a/shot_b`]",
+		},
+		{
+			name:    "Add tags",
+			command: `tag add dir_a/shot_a final hero`,
+			want:    "[`/dir_a/shot_a`]",
+		},
+		{
+			name:    "Stored tags",
+			command: `get dir_a/shot_a|tags`,
+			want:    `["final","hero"]`,
+		},
+		{
+			name:    "Tag selector",
+			command: `get **/(#hero)`,
+			want:    "[`/dir_a/shot_a`,`/dir_a/shot_b`]",
+		},
+		{
+			name:    "Multiple tag selector",
+			command: `get **/(#hero,#final)`,
+			want:    "[`/dir_a/shot_a`]",
+		},
+		{
+			name:    "Tagged function",
+			command: `get dir_a/shot_b|tagged("hero")&&!tagged("final")`,
+			want:    `true`,
+		},
+		{
+			name:    "Tag on strict schema",
+			command: `tag add dir_a/chair/leg hero`,
+			want:    "[`/dir_a/chair/leg`]",
+		},
+		{
+			name:    "Count tags",
+			command: `tags`,
+			want:    `{final: 1,hero: 3}`,
+		},
+		{
+			name:    "Remove tags",
+			command: `tag rm dir_a/shot_a final hero`,
+			want:    "[`/dir_a/shot_a`]",
+		},
+		{
+			name:    "List tags",
+			command: `tag ls dir_a/shot_*`,
+			want:    "[{Obj: `/dir_a/shot_a`,Tags: []},{Obj: `/dir_a/shot_b`,Tags: [\"hero\"]}]",
+		},
+		{
+			name:    "Removed tags attribute",
+			command: `get dir_a/shot_a|tags`,
+			want:    `none`,
+		},
+		{
+			name:    "Empty literals",
+			command: `get |[{},[]]`,
+			want:    `[{},[]]`,
+		},
+	}
+	runTests(t, tests)
+}
+
+func TestStorage(t *testing.T) {
+	initTest(t)
+	moved := "[`/`,`/dir_a/chair/`,`/dir_a/chair/leg`,`/dir_a/chair/seat`,`/dir_a/file_b`,`/dir_a/shot_a`,`/dir_a/shot_b`,`/file_a`]"
+	tests := []testCase{
+		{
+			name:    "Migrate to xattr",
+			command: `migrate --to xattr`,
+			want:    moved,
+		},
+		{
+			name:    "Read from xattr",
+			command: `get dir_a/file_b|fullName`,
+			want:    `"Bob Smith"`,
+		},
+		{
+			name:    "Write to xattr",
+			command: `set file_a count 3`,
+			want:    "[`/file_a`]",
+		},
+		{
+			name:    "Migrate back to sidecar",
+			command: `migrate --to sidecar`,
+			want:    moved,
+		},
+		{
+			name:    "Read migrated write",
+			command: `get file_a|count`,
+			want:    `3`,
+		},
+	}
+	runTests(t, tests[:3])
+	if _, err := os.Stat("/tmp/feta_test_tree/.feta/file_a._"); !os.IsNotExist(err) {
+		t.Errorf("Sidecar remained after migration: %v", err)
+	}
+	if _, err := os.Stat("/tmp/feta_test_tree/dir_a/chair/.feta/schema"); err != nil {
+		t.Errorf("Schema was removed by migration: %v", err)
+	}
+	runTests(t, tests[3:])
+}
+
+func TestDatabaseStorage(t *testing.T) {
+	initTest(t)
+	tests := []testCase{
+		{
+			name:    "Migrate to database",
+			command: `migrate --to db`,
+			want:    "[`/`,`/dir_a/chair/`,`/dir_a/chair/leg`,`/dir_a/chair/seat`,`/dir_a/file_b`,`/dir_a/shot_a`,`/dir_a/shot_b`,`/file_a`]",
+		},
+		{
+			name:    "Read from database",
+			command: `get dir_a/chair/|source.User`,
+			want:    `"Bob"`,
+		},
+		{
+			name:    "Write to database",
+			command: `tag add dir_a/shot_* hero`,
+			want:    "[`/dir_a/shot_a`,`/dir_a/shot_b`]",
+		},
+		{
+			name:    "Select from database",
+			command: `get **/(#hero)`,
+			want:    "[`/dir_a/shot_a`,`/dir_a/shot_b`]",
+		},
+		{
+			name:    "Read after reopening database",
+			command: `tags`,
+			want:    `{hero: 2}`,
+		},
+	}
+	runTests(t, tests)
+	if _, err := os.Stat("/tmp/feta_test_tree/dir_a/.feta/shot_a._"); !os.IsNotExist(err) {
+		t.Errorf("Sidecar remained after migration: %v", err)
+	}
+	db, err := bolt.Open("/tmp/feta_test_tree/.feta/meta.db", 0644, &bolt.Options{ReadOnly: true, Timeout: time.Second})
+	if err != nil {
+		t.Fatalf("Couldn't open database: %s", err)
+	}
+	defer db.Close()
+	if got := run(`get dir_a/shot_a|tags`); got != "[\"hero\"]\n" {
+		t.Errorf("Read beside another reader: %s", got)
+	}
+}
+
+func TestJournal(t *testing.T) {
+	initTest(t)
+	journal := `[{"Path":"/file_a","Data":"{User: \"Carol\"}"},{"Path":"/dir_a/shot_b","Data":null}]`
+	err := os.WriteFile("/tmp/feta_test_tree/.feta/journal", []byte(journal), 0644)
+	if err != nil {
+		t.Fatalf("Couldn't write journal: %s", err)
+	}
+	tests := []testCase{
+		{
+			name:    "Read before recovery",
+			command: `get file_a|User`,
+			want:    `"Alice"`,
+		},
+		{
+			name:    "Write recovers journal",
+			command: `set dir_a/shot_a label "A"`,
+			want:    "[`/dir_a/shot_a`]",
+		},
+		{
+			name:    "Restored value",
+			command: `get file_a|User`,
+			want:    `"Carol"`,
+		},
+		{
+			name:    "Removed metadata",
+			command: `get dir_a/shot_b|~`,
+			want:    `{defaulted: [],obj: {isDir: false,name: "shot_b",size: 0}}`,
+		},
+	}
+	runTests(t, tests)
+	if _, err := os.Stat("/tmp/feta_test_tree/.feta/journal"); !os.IsNotExist(err) {
+		t.Errorf("Journal remained after recovery: %v", err)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	initTest(t)
+	tests := []testCase{
+		{
+			name:    "First change",
+			command: `set file_a count 3`,
+			want:    "[`/file_a`]",
+		},
+		{
+			name:    "Second change",
+			command: `set file_a count 4`,
+			want:    "[`/file_a`]",
+		},
+		{
+			name:    "Multi-object change",
+			command: `tag add **/(?User) hero`,
+			want:    "[`/dir_a/file_b`,`/file_a`]",
+		},
+		{
+			name:    "History procedural",
+			command: `get file_a|map(history,h->[h.change,h.path,h.old,h.new])`,
+			want:    `[[1,"count",none,3],[2,"count",3,4],[3,"tags",none,["hero"]]]`,
+		},
+		{
+			name:    "History left out of dict",
+			command: `get file_a|`,
+			want:    `{User: "Alice",count: 4,defaulted: [],obj: {isDir: false,name: "file_a",size: 34},tags: ["hero"]}`,
+		},
+		{
+			name:    "Undo change",
+			command: `undo 2`,
+			want:    "[`/file_a`]",
+		},
+		{
+			name:    "Undone value",
+			command: `get file_a|count`,
+			want:    `3`,
+		},
+		{
+			name:    "Undo recorded",
+			command: `get file_a|history[-1].new`,
+			want:    `3`,
+		},
+		{
+			name:    "Undo multi-object change",
+			command: `undo 3`,
+			want:    "[`/dir_a/file_b`,`/file_a`]",
+		},
+		{
+			name:    "Undone tags",
+			command: `tags`,
+			want:    `{}`,
+		},
+		{
+			name:    "Attribute named like procedural",
+			command: `set dir_a/file_b history "rewritten"`,
+			want:    "[`/dir_a/file_b`]",
+		},
+		{
+			name:    "Attribute hides procedural",
+			command: `get dir_a/file_b|[history,defaulted]`,
+			want:    `["rewritten",[]]`,
+		},
+	}
+	runTests(t, tests)
+	if got := run(`log dir_a/file_b`); !strings.Contains(got, `Path: "tags"`) || strings.Contains(got, `"count"`) {
+		t.Errorf("Unexpected log: %s", got)
+	}
+}
+
+func TestSnapshots(t *testing.T) {
+	initTest(t)
+	tests := []testCase{
+		{
+			name:    "Create snapshot",
+			command: `snapshot create before`,
+			want:    `{Name: "before",Objects: 8}`,
+		},
+		{
+			name:    "Diff unchanged against live",
+			command: `diff before live`,
+			want:    `[]`,
+		},
+		{
+			name:    "Change attribute",
+			command: `set file_a User "Carol"`,
+			want:    "[`/file_a`]",
+		},
+		{
+			name:    "Add metadata",
+			command: `tag add dir_a/ hero`,
+			want:    "[`/dir_a/`]",
+		},
+		{
+			name:    "Create snapshot with stat info",
+			command: `snapshot create after -stat`,
+			want:    `{Name: "after",Objects: 9}`,
+		},
+		{
+			name:    "Diff snapshots",
+			command: `diff before after`,
+			want:    "[{Attributes: [{New: [\"hero\"],Old: none,Path: \"tags\"}],Change: \"added\",Obj: \"/dir_a/\"},{Attributes: [{New: \"Carol\",Old: \"Alice\",Path: \"User\"}],Change: \"modified\",Obj: \"/file_a\"}]",
+		},
+		{
+			name:    "Diff against live",
+			command: `diff after live dir_a/*`,
+			want:    `[]`,
+		},
+		{
+			name:    "Change after snapshot",
+			command: `set dir_a/shot_b label "B"`,
+			want:    "[`/dir_a/shot_b`]",
+		},
+		{
+			name:    "Diff query against live",
+			command: `diff before live dir_a/shot_*`,
+			want:    "[{Attributes: [{New: \"B\",Old: next.label,Path: \"label\"}],Change: \"modified\",Obj: \"/dir_a/shot_b\"}]",
+		},
+	}
+	runTests(t, tests)
+}
+
+func TestImport(t *testing.T) {
+	initTest(t)
+	files := map[string]string{
+		"/tmp/feta_import.json": `[{"path": "file_a", "status": "wip", "frames": 120}, {"path": "dir_a/nope", "status": "x"}]`,
+		"/tmp/feta_import.yaml": "/dir_a/file_b:\n  status: done\n",
+		"/tmp/feta_import.csv":  "name,info.frames\nshot_a,10\nshot_b,20\n",
+		"/tmp/feta_users.csv":   "User,reviewed\nAlice,true\n",
+		"/tmp/feta_cells.csv":   "path,nan,inf,padded,neg\nfile_a,NaN,Inf,0010,-1.5e3\n",
+		"/tmp/feta_fps.csv":     "name,info.fps\nshot_a,24\n",
+		"/tmp/feta_split.json":  `[{"path": "file_a", "a": 1}, {"path": "file_a", "b": 2}]`,
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Couldn't write import file: %s", err)
+		}
+	}
+	tests := []testCase{
+		{
+			name:    "Dry run",
+			command: `import -n /tmp/feta_import.json`,
+			want:    `{Changes: [{New: 120,Obj: "/file_a",Old: none,Path: "frames"},{New: "wip",Obj: "/file_a",Old: none,Path: "status"}],Unmatched: [{Path: "dir_a/nope",Record: 2}]}`,
+		},
+		{
+			name:    "Nothing written on dry run",
+			command: `get file_a|status`,
+			want:    `none`,
+		},
+		{
+			name:    "Import JSON",
+			command: `import /tmp/feta_import.json`,
+			want:    "{Imported: [`/file_a`],Unmatched: [{Path: \"dir_a/nope\",Record: 2}]}",
+		},
+		{
+			name:    "Imported values",
+			command: `get file_a|[User,status,frames]`,
+			want:    `["Alice","wip",120]`,
+		},
+		{
+			name:    "Replace from YAML",
+			command: `import -replace /tmp/feta_import.yaml`,
+			want:    "{Imported: [`/dir_a/file_b`],Unmatched: []}",
+		},
+		{
+			name:    "Replaced values",
+			command: `get dir_a/file_b|[status,User]`,
+			want:    `["done",none]`,
+		},
+		{
+			name:    "Import CSV by query",
+			command: `import -key name -query dir_a/*(?obj.name==$name) /tmp/feta_import.csv`,
+			want:    "{Imported: [`/dir_a/shot_a`,`/dir_a/shot_b`],Unmatched: []}",
+		},
+		{
+			name:    "Imported nested values",
+			command: `get dir_a/shot_*|info.frames`,
+			want:    `[{Obj: ` + "`/dir_a/shot_a`" + `,Value: 10},{Obj: ` + "`/dir_a/shot_b`" + `,Value: 20}]`,
+		},
+		{
+			name:    "Key field not imported by query",
+			command: `get dir_a/shot_a|name`,
+			want:    `none`,
+		},
+		{
+			name:    "Merge nested dict",
+			command: `import -key name -query dir_a/*(?obj.name==$name) /tmp/feta_fps.csv`,
+			want:    "{Imported: [`/dir_a/shot_a`],Unmatched: []}",
+		},
+		{
+			name:    "Merged nested values",
+			command: `get dir_a/shot_a|info`,
+			want:    `{fps: 24,frames: 10}`,
+		},
+		{
+			name:    "Record field doesn't shadow attribute",
+			command: `import -query **/(?User==$User) /tmp/feta_users.csv`,
+			want:    "{Imported: [`/file_a`],Unmatched: []}",
+		},
+		{
+			name:    "Import CSV cells",
+			command: `import /tmp/feta_cells.csv`,
+			want:    "{Imported: [`/file_a`],Unmatched: []}",
+		},
+		{
+			name:    "Only number literals are numbers",
+			command: `get file_a|[nan,inf,padded,neg]`,
+			want:    `["NaN","Inf","0010",-1500]`,
+		},
+		{
+			name:    "Replace with several records",
+			command: `import -replace /tmp/feta_split.json`,
+			want:    "{Imported: [`/file_a`],Unmatched: []}",
+		},
+		{
+			name:    "Replaced by all records",
+			command: `get file_a|[a,b,User]`,
+			want:    `[1,2,none]`,
+		},
+	}
+	runTests(t, tests)
+}
+
+func TestArchive(t *testing.T) {
+	initTest(t)
+	exported := run(`export dir_a/*`)
+	for _, want := range []string{`"/dir_a/file_b":{`, `"Meta":{"User":"Bob",`, `"fullName":{"$expr":"first+\" \"+last"}`, `"IsDir":true`, `"Size":0`} {
+		if !strings.Contains(exported, want) {
+			t.Errorf("Export doesn't contain %s: %s", want, exported)
+		}
+	}
+	if strings.Contains(exported, `"/file_a"`) {
+		t.Errorf("Export contains unselected object: %s", exported)
+	}
+	if err := os.WriteFile("/tmp/feta_exported.json", []byte(exported), 0644); err != nil {
+		t.Fatalf("Couldn't write export: %s", err)
+	}
+	if got := run(`import -archive -n /tmp/feta_exported.json`); got != "{Changes: [],Unmatched: []}\n" {
+		t.Errorf("Export doesn't restore as is: %s", got)
+	}
+	archive := `{"Objects": {
+		"/dir_a/file_b": {"Meta": {"User": "Bob", "status": "final", "due": {"$expr": "d\"2026-10-18\""}}, "Size": 0},
+		"/dir_a/gone": {"Meta": {"User": "Nobody"}},
+		"/file_a": {"Meta": {"User": "Alice"}, "IsDir": true}
+	}}`
+	if err := os.WriteFile("/tmp/feta_archive.json", []byte(archive), 0644); err != nil {
+		t.Fatalf("Couldn't write archive: %s", err)
+	}
+	unmatched := `Unmatched: [{Path: "/dir_a/gone",Reason: "missing"},{Path: "/file_a",Reason: "type mismatch"}]`
+	tests := []testCase{
+		{
+			name:    "Dry run archive import",
+			command: `import -archive -n /tmp/feta_archive.json`,
+			want:    `{Changes: [{New: d"2026-10-18",Obj: "/dir_a/file_b",Old: none,Path: "due"},{New: none,Obj: "/dir_a/file_b",Old: "Bob",Path: "first"},{New: none,Obj: "/dir_a/file_b",Old: first+" "+last,Path: "fullName"},{New: none,Obj: "/dir_a/file_b",Old: obj.size>1000000000,Path: "isLarge"},{New: none,Obj: "/dir_a/file_b",Old: "Smith",Path: "last"},{New: "final",Obj: "/dir_a/file_b",Old: none,Path: "status"}],` + unmatched + `}`,
+		},
+		{
+			name:    "Import archive",
+			command: `import -archive /tmp/feta_archive.json`,
+			want:    "{Imported: [`/dir_a/file_b`]," + unmatched + "}",
+		},
+		{
+			name:    "Restored sidecar",
+			command: `get dir_a/file_b|[User,status,first,due]`,
+			want:    `["Bob","final",none,d"2026-10-18"]`,
+		},
+	}
+	runTests(t, tests)
+}
+
+func TestFormat(t *testing.T) {
+	initTest(t)
+	messy := "{ note: \"say \\\"hi\\\"\\tnow\",\n    b:[1,2],  a:{y:1,x:  2}}"
+	if err := os.WriteFile(".feta/file_a._", []byte(messy), 0644); err != nil {
+		t.Fatalf("Couldn't write sidecar: %s", err)
+	}
+	exitCode := 0
+	exit = func(code int) { exitCode = code }
+	defer func() { exit = os.Exit }()
+	tests := []testCase{
+		{
+			name:    "Check unformatted",
+			command: `fmt -check file_a`,
+			want:    "[`/file_a`]",
+		},
+		{
+			name:    "Format",
+			command: `fmt file_a`,
+			want:    "[`/file_a`]",
+		},
+		{
+			name:    "Escaped string",
+			command: `get file_a|note`,
+			want:    `"say \"hi\"\tnow"`,
+		},
+		{
+			name:    "Check formatted",
+			command: `fmt -check file_a`,
+			want:    "[]",
+		},
+	}
+	runTests(t, tests)
+	if exitCode != 1 {
+		t.Errorf("Format check exited with %d instead of 1", exitCode)
+	}
+	data, err := os.ReadFile(".feta/file_a._")
+	if err != nil {
+		t.Fatalf("Couldn't read sidecar: %s", err)
+	}
+	want := "{\n  a: {\n    x: 2,\n    y: 1\n  },\n  b: [\n    1,\n    2\n  ],\n  note: \"say \\\"hi\\\"\\tnow\"\n}\n"
+	if string(data) != want {
+		t.Errorf("Formatted sidecar:\n%s\nwant:\n%s", data, want)
+	}
+}
+
+func TestComments(t *testing.T) {
+	initTest(t)
+	sidecar := `# Notes about file_a
+{
+  # who owns it
+  User: "Alice",
+  tags: [
+    "hero", # main asset
+    "final",
+  ],
+  size: 3, # in meters
+  # nothing after this
+}
+`
+	if err := os.WriteFile(".feta/file_a._", []byte(sidecar), 0644); err != nil {
+		t.Fatalf("Couldn't write sidecar: %s", err)
+	}
+	last := "{half: 7 // 2, n: 8, # eight\n}"
+	if err := os.WriteFile("dir_a/.feta/shot_b._", []byte(last), 0644); err != nil {
+		t.Fatalf("Couldn't write sidecar: %s", err)
+	}
+	tests := []testCase{
+		{
+			name:    "Read commented sidecar",
+			command: `get file_a|[User,size,tags]`,
+			want:    `["Alice",3,["hero","final"]]`,
+		},
+		{
+			name:    "Set value with comments",
+			command: `set dir_a/file_b nums [1,#one` + "\n" + `2,]`,
+			want:    "[`/dir_a/file_b`]",
+		},
+		{
+			name:    "Read value with comments",
+			command: `get dir_a/file_b|nums`,
+			want:    `[1,2]`,
+		},
+		{
+			name:    "Set in commented sidecar",
+			command: `set file_a size 4`,
+			want:    "[`/file_a`]",
+		},
+		{
+			name:    "Floor division beside comment",
+			command: `get dir_a/shot_b|[half,n]`,
+			want:    `[3,8]`,
+		},
+		{
+			name:    "Format comment on last entry",
+			command: `fmt dir_a/shot_b`,
+			want:    "[`/dir_a/shot_b`]",
+		},
+		{
+			name:    "Formatted comment on last entry",
+			command: `get dir_a/shot_b|[half,n]`,
+			want:    `[3,8]`,
+		},
+		{
+			name:    "Commented sidecar stays formatted",
+			command: `fmt -check file_a`,
+			want:    "[]",
+		},
+	}
+	runTests(t, tests)
+	data, err := os.ReadFile(".feta/file_a._")
+	if err != nil {
+		t.Fatalf("Couldn't read sidecar: %s", err)
+	}
+	want := `# Notes about file_a
+{
+  # who owns it
+  User: "Alice",
+  size: 4, # in meters
+  tags: [
+    "hero", # main asset
+    "final"
+  ]
+  # nothing after this
+}
+`
+	if string(data) != want {
+		t.Errorf("Rewritten sidecar:\n%s\nwant:\n%s", data, want)
+	}
+	data, err = os.ReadFile("dir_a/.feta/shot_b._")
+	if err != nil {
+		t.Fatalf("Couldn't read sidecar: %s", err)
+	}
+	want = "{\n  half: 7//2,\n  n: 8, # eight\n}\n"
+	if string(data) != want {
+		t.Errorf("Formatted sidecar:\n%s\nwant:\n%s", data, want)
+	}
+}
+
+func TestQuotedKeys(t *testing.T) {
+	initTest(t)
+	sidecar := `{"shot-010": {frames: 24}, "a.b": 1, 1080p: true, User: "Alice"}`
+	if err := os.WriteFile(".feta/file_a._", []byte(sidecar), 0644); err != nil {
+		t.Fatalf("Couldn't write sidecar: %s", err)
+	}
+	tests := []testCase{
+		{
+			name:    "Index quoted key",
+			command: `get file_a|~["shot-010"].frames`,
+			want:    `24`,
+		},
+		{
+			name:    "Index key with dot",
+			command: `get file_a|~["a.b"]`,
+			want:    `1`,
+		},
+		{
+			name:    "Set at quoted key",
+			command: `set file_a ["shot-010"].frames 25`,
+			want:    "[`/file_a`]",
+		},
+		{
+			name:    "Undo at quoted key",
+			command: `undo 1`,
+			want:    "[`/file_a`]",
+		},
+		{
+			name:    "Undone value",
+			command: `get file_a|~["shot-010"].frames`,
+			want:    `24`,
+		},
+	}
+	runTests(t, tests)
+	data, err := os.ReadFile(".feta/file_a._")
+	if err != nil {
+		t.Fatalf("Couldn't read sidecar: %s", err)
+	}
+	want := "{\n  1080p: true,\n  User: \"Alice\",\n  \"a.b\": 1,\n  \"shot-010\": {\n    frames: 24\n  }\n}\n"
+	if string(data) != want {
+		t.Errorf("Rewritten sidecar:\n%s\nwant:\n%s", data, want)
+	}
+}
+
+func TestDates(t *testing.T) {
+	initTest(t)
+	sidecar := `{User: "Alice", deadline: d"2026-10-18", review: d"2026-10-18T12:30:00Z"}`
+	if err := os.WriteFile(".feta/file_a._", []byte(sidecar), 0644); err != nil {
+		t.Fatalf("Couldn't write sidecar: %s", err)
+	}
+	schema := `{attributes: {deadline: {type: "date"}, review: {type: "duration"}, wait: {type: "duration"}}}`
+	if err := os.WriteFile(".feta/schema", []byte(schema), 0644); err != nil {
+		t.Fatalf("Couldn't write schema: %s", err)
+	}
+	exitCode := 0
+	exit = func(code int) { exitCode = code }
+	defer func() { exit = os.Exit }()
+	tests := []testCase{
+		{
+			name:    "Date literal",
+			command: `get file_a|[deadline,review]`,
+			want:    `[d"2026-10-18",d"2026-10-18T12:30:00Z"]`,
+		},
+		{
+			name:    "Add duration to date",
+			command: `get file_a|deadline+3d`,
+			want:    `d"2026-10-21"`,
+		},
+		{
+			name:    "Subtract dates",
+			command: `get file_a|[review-deadline,deadline-review]`,
+			want:    `[12h30m,-12h30m]`,
+		},
+		{
+			name:    "Compare dates",
+			command: `get file_a|[deadline<review,deadline+1w>d"2026-10-24",now()>deadline-1000d]`,
+			want:    `[true,true,true]`,
+		},
+		{
+			name:    "Duration arithmetic",
+			command: `get file_a|[1d12h*2,90m/2,1h/30m,1.5s+500ms,-2d]`,
+			want:    `[3d,45m,2,2s,-2d]`,
+		},
+		{
+			name:    "Format date",
+			command: `get file_a|format_date(review,"%d/%m/%Y-%H:%M")`,
+			want:    `"18/10/2026-12:30"`,
+		},
+		{
+			name:    "Parse date",
+			command: `get file_a|[date("18.10.2026","%d.%m.%Y")==deadline,date("2026-10-18"),date(0)]`,
+			want:    `[true,d"2026-10-18",d"1970-01-01"]`,
+		},
+		{
+			name:    "Literal text in format",
+			command: `get file_a|[format_date(review,"v1-Jan-%Y_15%%"),date("v1-Jan-2026_15%","v1-Jan-%Y_15%%")==d"2026-01-01"]`,
+			want:    `["v1-Jan-2026_15%",true]`,
+		},
+		{
+			name:    "Literal text mismatch",
+			command: `get file_a|date("v2-2026","v1-%Y")`,
+			want:    `error{"Couldn't parse date 'v2-2026' with format 'v1-%Y'."}`,
+		},
+		{
+			name:    "Convert durations",
+			command: `get file_a|[duration("1d12h"),duration(90),number(1h),string(deadline),string(2d)]`,
+			want:    `[1d12h,1m30s,3600,"2026-10-18","2d"]`,
+		},
+		{
+			name:    "Invalid date",
+			command: `get file_a|date("someday")`,
+			want:    `error{"Invalid date 'someday'."}`,
+		},
+		{
+			name:    "Dates too far apart",
+			command: `get file_a|d"1700-01-01"-d"2026-01-01"`,
+			want:    `error{"Duration out of range."}`,
+		},
+		{
+			name:    "Duration product overflow",
+			command: `get file_a|1e300*1h`,
+			want:    `error{"Duration out of range."}`,
+		},
+		{
+			name:    "Smallest duration",
+			command: `get file_a|-15250w-1d-23h-47m-16s-854ms-775us-808ns`,
+			want:    `-106751d23h47m16s854ms775us808ns`,
+		},
+		{
+			name:    "Negated smallest duration",
+			command: `get file_a|-(-15250w-1d-23h-47m-16s-854ms-775us-808ns)`,
+			want:    `error{"Duration out of range."}`,
+		},
+		{
+			name:    "Set duration",
+			command: `set file_a wait 90m`,
+			want:    "[`/file_a`]",
+		},
+		{
+			name:    "Validate date and duration types",
+			command: `validate file_a`,
+			want:    "[{Obj: `/file_a`,Violations: [\"Attribute 'review' should be [duration], not date\"]}]",
+		},
+	}
+	runTests(t, tests)
+	if exitCode != 1 {
+		t.Errorf("Validate exited with %d instead of 1", exitCode)
+	}
+	data, err := os.ReadFile(".feta/file_a._")
+	if err != nil {
+		t.Fatalf("Couldn't read sidecar: %s", err)
+	}
+	want := "{\n  User: \"Alice\",\n  deadline: d\"2026-10-18\",\n  review: d\"2026-10-18T12:30:00Z\",\n  wait: 1h30m\n}\n"
+	if string(data) != want {
+		t.Errorf("Rewritten sidecar:\n%s\nwant:\n%s", data, want)
+	}
+}
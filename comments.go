@@ -0,0 +1,227 @@
+package feta
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// The comments of a dict entry or list element.
+type comments struct {
+	before []string // comment lines preceding it
+	after  []string // comment on its line, or the lines following the root
+	end    []string // comment lines before the closing bracket of a container
+}
+
+// A commentTable holds the comments of a parsed sidecar by the path of the
+// entry or element they belong to, where the root is the empty path. Comments
+// inside expressions other than dicts and lists aren't kept.
+type commentTable map[string]*comments
+
+// A commentTok is a comment found by the parser at an input offset.
+type commentTok struct {
+	text   string
+	offset int
+}
+
+// A containerItem is a dict entry or list element found by the parser. Its
+// name is its part of an attribute path.
+type containerItem struct {
+	name  string
+	key   string
+	value fExpr
+}
+
+// A parsedSidecar is the result of the Sidecar entrypoint.
+type parsedSidecar struct {
+	expr     fExpr
+	comments commentTable
+}
+
+// ownedComments is the comment table of a container in the global store of
+// the parser, which holds on to the container so that its address, which the
+// key is made of, isn't reused during the parse.
+type ownedComments struct {
+	owner fExpr
+	table commentTable
+}
+
+func commentKey(v fExpr) string {
+	return fmt.Sprintf("comments %p", v)
+}
+
+func storedComments(store storeDict, v fExpr) commentTable {
+	owned, _ := store[commentKey(v)].(ownedComments)
+	return owned.table
+}
+
+// flattenSeq flattens the items and comments matched by the Dict and List
+// rules into one sequence, dropping the punctuation.
+func flattenSeq(parts []interface{}) []interface{} {
+	seq := []interface{}{}
+	for _, part := range parts {
+		switch p := part.(type) {
+		case []interface{}:
+			seq = append(seq, flattenSeq(p)...)
+		case commentTok, containerItem, fExpr:
+			seq = append(seq, p)
+		}
+	}
+	return seq
+}
+
+func registerComments(c *current, v fExpr, seq []interface{}) {
+	table := attachComments(c.text, c.pos.offset, seq, c.globalStore)
+	if table != nil {
+		c.globalStore[commentKey(v)] = ownedComments{v, table}
+	}
+}
+
+func newDict(c *current, parts ...interface{}) fDict {
+	seq := flattenSeq(parts)
+	dict := fDict{}
+	for _, tok := range seq {
+		if item, isItem := tok.(containerItem); isItem {
+			dict[item.key] = item.value
+		}
+	}
+	registerComments(c, dict, seq)
+	return dict
+}
+
+func newList(c *current, parts ...interface{}) fList {
+	seq := flattenSeq(parts)
+	list := make(fList, 0, len(seq)+1)
+	for i, tok := range seq {
+		if expr, isExpr := tok.(fExpr); isExpr {
+			seq[i] = containerItem{name: indexName(len(list)), value: expr}
+			list = append(list, expr)
+		}
+	}
+	registerComments(c, list, seq)
+	return list
+}
+
+// newSidecar adds the comments around the root of a sidecar to its table.
+func newSidecar(c *current, expr fExpr, lead interface{}, trail interface{}) *parsedSidecar {
+	table := storedComments(c.globalStore, expr)
+	if table == nil {
+		table = commentTable{}
+	}
+	for _, tok := range flattenSeq(toList(lead)) {
+		table.get("").before = append(table.get("").before, tok.(commentTok).text)
+	}
+	for _, tok := range flattenSeq(toList(trail)) {
+		table.get("").after = append(table.get("").after, tok.(commentTok).text)
+	}
+	if len(table) == 0 {
+		table = nil
+	}
+	return &parsedSidecar{expr, table}
+}
+
+func indexName(i int) string {
+	return "[" + strconv.Itoa(i) + "]"
+}
+
+func (table commentTable) get(path string) *comments {
+	cs, exists := table[path]
+	if !exists {
+		cs = &comments{}
+		table[path] = cs
+	}
+	return cs
+}
+
+// onSameLine reports whether only blanks separate the byte at i from the
+// preceding content of the line.
+func onSameLine(text []byte, i int) bool {
+	for i--; i >= 0; i-- {
+		switch text[i] {
+		case ' ', '\t':
+			continue
+		case '\n', '\r':
+			return false
+		}
+		return true
+	}
+	return false
+}
+
+// attachComments builds the comment table of a container from the sequence
+// of its items and comments, taking the tables of nested containers from the
+// store. A comment on the line of an item belongs to it, other comments to
+// the item following them or to the end of the container.
+func attachComments(text []byte, start int, seq []interface{}, store storeDict) commentTable {
+	table := commentTable{}
+	var last string
+	var pending []string
+	for _, tok := range seq {
+		switch t := tok.(type) {
+		case commentTok:
+			if last != "" && onSameLine(text, t.offset-start) {
+				cs := table.get(last)
+				cs.after = append(cs.after, t.text)
+			} else {
+				pending = append(pending, t.text)
+			}
+		case containerItem:
+			last = t.name
+			if len(pending) > 0 {
+				table.get(last).before = pending
+				pending = nil
+			}
+			nested := storedComments(store, t.value)
+			for p, cs := range nested {
+				if p == "" {
+					table.get(last).end = cs.end
+				} else {
+					table[childPath(last, p)] = cs
+				}
+			}
+		}
+	}
+	if len(pending) > 0 {
+		table.get("").end = pending
+	}
+	if len(table) == 0 {
+		return nil
+	}
+	return table
+}
+
+// parseSidecar parses the text of a sidecar or other metadata file, which
+// must hold a dict, and returns its comments too.
+func parseSidecar(path string, data []byte) (fDict, commentTable, error) {
+	parsed, err := Parse(path, data, Entrypoint("Sidecar"))
+	if err != nil {
+		return nil, nil, err
+	}
+	sc := parsed.(*parsedSidecar)
+	dict, isDict := sc.expr.(fDict)
+	if !isDict {
+		return nil, nil, fmt.Errorf("%s doesn't contain a dict", path)
+	}
+	return dict, sc.comments, nil
+}
+
+// marshalMeta serializes a sidecar dict with its comments.
+func marshalMeta(meta fDict, table commentTable) []byte {
+	res := make([]byte, 0, startSize)
+	st := &mshState{res: res, pretty: true, comments: table}
+	root := table[""]
+	if root != nil {
+		st.appendLines(root.before, "")
+	}
+	meta.marshal(st)
+	st.res = append(st.res, '\n')
+	if root != nil {
+		st.appendLines(root.after, "")
+	}
+	return st.res
+}
+
+func (st *mshState) appendLines(lines []string, ind string) {
+	for _, line := range lines {
+		st.res = append(st.res, (ind + line + "\n")...)
+	}
+}
@@ -1,11 +1,40 @@
 package feta
 
+import (
+	"fmt"
+	"strings"
+)
+
 type flags struct {
 	Verbose  bool
 	SitePath string
 	SysAbs   bool
 	UglyJSON bool
 	RawOut   bool
+	Vars     varFlags
+}
+
+// varFlags collects the variables set with repeated -var name=value flags.
+type varFlags map[string]string
+
+func (vars *varFlags) String() string {
+	pairs := make([]string, 0, len(*vars))
+	for k, v := range *vars {
+		pairs = append(pairs, k+"="+v)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (vars *varFlags) Set(value string) error {
+	kv := strings.SplitN(value, "=", 2)
+	if len(kv) != 2 || kv[0] == "" {
+		return fmt.Errorf("Variable must be given as name=value, got '%s'", value)
+	}
+	if *vars == nil {
+		*vars = varFlags{}
+	}
+	(*vars)[kv[0]] = kv[1]
+	return nil
 }
 
 var Flags flags
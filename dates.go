@@ -0,0 +1,354 @@
+package feta
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+// dateLayouts are the layouts accepted by date literals and date(), tried in
+// order. Times without a zone are taken as UTC.
+var dateLayouts = []string{
+	dateLayout,
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+}
+
+func parseDate(s string) (fDate, error) {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return fDate(t), nil
+		}
+	}
+	return fDate{}, fmt.Errorf("Invalid date '%s'", s)
+}
+
+// formatDate returns the text of a date literal, which is only the day for
+// midnight in UTC.
+func formatDate(d fDate) string {
+	t := time.Time(d)
+	if t.Location() == time.UTC && t.Equal(t.Truncate(24*time.Hour)) {
+		return t.Format(dateLayout)
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+// durationUnits are the units of duration literals from the longest, as
+// they are written by formatDuration.
+var durationUnits = []struct {
+	name string
+	size time.Duration
+}{
+	{"w", 7 * 24 * time.Hour},
+	{"d", 24 * time.Hour},
+	{"h", time.Hour},
+	{"m", time.Minute},
+	{"s", time.Second},
+	{"ms", time.Millisecond},
+	{"us", time.Microsecond},
+	{"ns", time.Nanosecond},
+}
+
+// parseDuration parses a sequence of numbers with units, like 1d12h, with an
+// optional leading minus sign.
+func parseDuration(s string) (fDuration, error) {
+	rest := strings.TrimPrefix(s, "-")
+	if rest == "" {
+		return 0, fmt.Errorf("Invalid duration '%s'", s)
+	}
+	var total float64
+	for rest != "" {
+		end := strings.IndexFunc(rest, func(r rune) bool {
+			return (r < '0' || r > '9') && r != '.'
+		})
+		if end <= 0 {
+			return 0, fmt.Errorf("Invalid duration '%s'", s)
+		}
+		n, err := strconv.ParseFloat(rest[:end], 64)
+		if err != nil {
+			return 0, fmt.Errorf("Invalid duration '%s'", s)
+		}
+		rest = rest[end:]
+		name, unit := "", time.Duration(0)
+		for _, u := range durationUnits {
+			if strings.HasPrefix(rest, u.name) && len(u.name) > len(name) {
+				name, unit = u.name, u.size
+			}
+		}
+		if unit == 0 {
+			return 0, fmt.Errorf("Invalid duration '%s'", s)
+		}
+		rest = rest[len(name):]
+		total += n * float64(unit)
+	}
+	if strings.HasPrefix(s, "-") {
+		total = -total
+	}
+	d, isDuration := durationOf(total).(fDuration)
+	if !isDuration {
+		return 0, fmt.Errorf("Duration '%s' is too long", s)
+	}
+	return d, nil
+}
+
+// durationOf converts a number of nanoseconds to a duration, or to an error
+// if it doesn't fit.
+func durationOf(ns float64) fExpr {
+	if math.IsNaN(ns) || ns >= math.MaxInt64 || ns < math.MinInt64 {
+		return fError{"Duration out of range."}
+	}
+	return fDuration(ns)
+}
+
+// addDurations adds or subtracts two durations, failing on overflow.
+func addDurations(l fDuration, r fDuration, op byte) fExpr {
+	var sum fDuration
+	var overflow bool
+	if op == '+' {
+		sum = l + r
+		overflow = (r > 0 && sum < l) || (r < 0 && sum > l)
+	} else {
+		sum = l - r
+		overflow = (r > 0 && sum > l) || (r < 0 && sum < l)
+	}
+	if overflow {
+		return fError{"Duration out of range."}
+	}
+	return sum
+}
+
+// dateDiff returns the duration from b to a, failing when it is too long to
+// be a duration.
+func dateDiff(a fDate, b fDate) fExpr {
+	d := time.Time(a).Sub(time.Time(b))
+	if !time.Time(b).Add(d).Equal(time.Time(a)) {
+		return fError{"Duration out of range."}
+	}
+	return fDuration(d)
+}
+
+// formatDuration writes a duration in days and smaller units, leaving out
+// the ones that are zero.
+func formatDuration(d fDuration) string {
+	if d == 0 {
+		return "0s"
+	}
+	var b strings.Builder
+	// The magnitude is unsigned, as the smallest duration has no positive
+	// counterpart.
+	n := uint64(d)
+	if d < 0 {
+		b.WriteByte('-')
+		n = uint64(-(d + 1)) + 1
+	}
+	for _, u := range durationUnits[1:] {
+		size := uint64(u.size)
+		if n >= size {
+			b.WriteString(strconv.FormatUint(n/size, 10) + u.name)
+			n %= size
+		}
+	}
+	return b.String()
+}
+
+// A strftimeElm is the Go layout element of a strftime directive, with the
+// pattern of the text it stands for.
+type strftimeElm struct {
+	layout  string
+	pattern string
+}
+
+// strftimeElms maps strftime directives to their layout elements.
+var strftimeElms = map[byte]strftimeElm{
+	'Y': {"2006", `\d{4}`},
+	'y': {"06", `\d{2}`},
+	'm': {"01", `\d{2}`},
+	'd': {"02", `\d{2}`},
+	'H': {"15", `\d{2}`},
+	'I': {"03", `\d{2}`},
+	'M': {"04", `\d{2}`},
+	'S': {"05", `\d{2}`},
+	'p': {"PM", `[AP]M`},
+	'b': {"Jan", `[A-Za-z]{3}`},
+	'B': {"January", `[A-Za-z]+`},
+	'a': {"Mon", `[A-Za-z]{3}`},
+	'A': {"Monday", `[A-Za-z]+`},
+	'z': {"-0700", `[+-]\d{4}`},
+	'Z': {"MST", `[A-Za-z]+|[+-]\d+`},
+}
+
+// A strftimePart is either literal text or a directive of a strftime
+// format. Literal text is kept apart from the layout elements, as Go would
+// read digits and names in it as elements too.
+type strftimePart struct {
+	text string
+	elm  *strftimeElm
+}
+
+// splitStrftime splits a strftime format into literal text and directives.
+func splitStrftime(format string) ([]strftimePart, fExpr) {
+	var parts []strftimePart
+	var text strings.Builder
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			text.WriteByte(format[i])
+			continue
+		}
+		i++
+		if i == len(format) {
+			return nil, fError{"Format '" + format + "' ends in '%'."}
+		}
+		if format[i] == '%' {
+			text.WriteByte('%')
+			continue
+		}
+		elm, exists := strftimeElms[format[i]]
+		if !exists {
+			return nil, fError{"Unknown directive '%" + string(format[i]) + "' in format '" + format + "'."}
+		}
+		if text.Len() > 0 {
+			parts = append(parts, strftimePart{text: text.String()})
+			text.Reset()
+		}
+		parts = append(parts, strftimePart{elm: &elm})
+	}
+	if text.Len() > 0 {
+		parts = append(parts, strftimePart{text: text.String()})
+	}
+	return parts, nil
+}
+
+// formatStrftime formats a time with a strftime format.
+func formatStrftime(t time.Time, format string) (string, fExpr) {
+	parts, fErr := splitStrftime(format)
+	if fErr != nil {
+		return "", fErr
+	}
+	var b strings.Builder
+	for _, part := range parts {
+		if part.elm == nil {
+			b.WriteString(part.text)
+		} else {
+			b.WriteString(t.Format(part.elm.layout))
+		}
+	}
+	return b.String(), nil
+}
+
+// parseStrftime parses a time with a strftime format. The literal text is
+// matched first, then the text of the directives is parsed with a layout of
+// only their elements.
+func parseStrftime(s string, format string) (time.Time, fExpr) {
+	parts, fErr := splitStrftime(format)
+	if fErr != nil {
+		return time.Time{}, fErr
+	}
+	var rex strings.Builder
+	var layout []string
+	rex.WriteString("^")
+	for _, part := range parts {
+		if part.elm == nil {
+			rex.WriteString(regexp.QuoteMeta(part.text))
+		} else {
+			rex.WriteString("(" + part.elm.pattern + ")")
+			layout = append(layout, part.elm.layout)
+		}
+	}
+	rex.WriteString("$")
+	fail := fError{"Couldn't parse date '" + s + "' with format '" + format + "'."}
+	match := regexp.MustCompile(rex.String()).FindStringSubmatch(s)
+	if match == nil {
+		return time.Time{}, fail
+	}
+	t, err := time.Parse(strings.Join(layout, " "), strings.Join(match[1:], " "))
+	if err != nil {
+		return time.Time{}, fail
+	}
+	return t, nil
+}
+
+func nowFn(ctx *context, args []fExpr) fExpr {
+	if fErr := checkArgs("now", args, 0); fErr != nil {
+		return fErr
+	}
+	return fDate(time.Now().UTC())
+}
+
+// dateFn converts a string in one of the date layouts, or in the strftime
+// format given as second argument, or a number of seconds since the Unix
+// epoch to a date.
+func dateFn(ctx *context, args []fExpr) fExpr {
+	if len(args) == 2 {
+		strs, fErr := stringArgs("date", args)
+		if fErr != nil {
+			return fErr
+		}
+		t, fErr := parseStrftime(strs[0], strs[1])
+		if fErr != nil {
+			return fErr
+		}
+		return fDate(t)
+	}
+	if fErr := checkArgs("date", args, 1); fErr != nil {
+		return fErr
+	}
+	switch v := args[0].(type) {
+	case fDate:
+		return v
+	case fString:
+		d, err := parseDate(string(v))
+		if err != nil {
+			return fError{err.Error() + "."}
+		}
+		return d
+	case fNumber:
+		sec, frac := math.Modf(float64(v))
+		return fDate(time.Unix(int64(sec), int64(frac*1e9)).UTC())
+	}
+	return fError{"date() only accepts dates, strings and numbers."}
+}
+
+// durationFn converts a string like "1d12h" or a number of seconds to a
+// duration.
+func durationFn(ctx *context, args []fExpr) fExpr {
+	if fErr := checkArgs("duration", args, 1); fErr != nil {
+		return fErr
+	}
+	switch v := args[0].(type) {
+	case fDuration:
+		return v
+	case fString:
+		d, err := parseDuration(strings.TrimSpace(string(v)))
+		if err != nil {
+			return fError{err.Error() + "."}
+		}
+		return d
+	case fNumber:
+		return durationOf(float64(v) * float64(time.Second))
+	}
+	return fError{"duration() only accepts durations, strings and numbers."}
+}
+
+func formatDateFn(ctx *context, args []fExpr) fExpr {
+	if fErr := checkArgs("format_date", args, 2); fErr != nil {
+		return fErr
+	}
+	d, isDate := args[0].(fDate)
+	format, isStr := args[1].(fString)
+	if !isDate || !isStr {
+		return fError{"format_date() takes a date and a format string."}
+	}
+	res, fErr := formatStrftime(time.Time(d), string(format))
+	if fErr != nil {
+		return fErr
+	}
+	return fString(res)
+}
@@ -1,5 +1,13 @@
 package feta
 
+import (
+	"math"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+)
+
 type resolver interface {
 	setNextAndRaw(resolver, bool)
 	resolve(*context, fExpr) fExpr
@@ -68,6 +76,13 @@ func (node *indexRes) resolve(ctx *context, ns fExpr) fExpr {
 	if fErr, ok := index.(fError); ok {
 		return fErr
 	}
+	if o, isObj := ns.(*object); isObj {
+		var fErr fExpr
+		if ctx, fErr = ctx.enter(o); fErr != nil {
+			return fErr
+		}
+		ns = ctx.meta
+	}
 	var res fExpr
 	var exists bool
 	switch v := ns.(type) {
@@ -85,35 +100,214 @@ func (node *indexRes) resolve(ctx *context, ns fExpr) fExpr {
 		if !isNum {
 			return fError{"Lists can only be indexed with numbers."}
 		}
-		ii := int(i)
-		if ii > len(v)-1 || ii < 0 {
+		ii, inRange := seqIndex(int(i), len(v))
+		if !inRange {
 			return fError{"Index out of range."}
 		}
 		res = v[ii]
+	case fString:
+		i, isNum := index.(fNumber)
+		if !isNum {
+			return fError{"Strings can only be indexed with numbers."}
+		}
+		runes := []rune(string(v))
+		ii, inRange := seqIndex(int(i), len(runes))
+		if !inRange {
+			return fError{"Index out of range."}
+		}
+		res = fString(runes[ii])
 	default:
-		return fError{"Only lists and dicts can be indexed."}
+		return fError{"Only lists, dicts and strings can be indexed."}
 	}
-	if node.next == nil {
-		if node.raw {
+	return resolveNext(ctx, node.next, node.raw, res)
+}
+
+// seqIndex converts a possibly negative index to a position in a sequence of
+// the given length.
+func seqIndex(i int, length int) (int, bool) {
+	if i < 0 {
+		i += length
+	}
+	return i, i >= 0 && i < length
+}
+
+// resolveNext continues the resolution chain with the value found by the
+// current resolver.
+func resolveNext(ctx *context, next resolver, raw bool, res fExpr) fExpr {
+	if next == nil {
+		if raw {
 			return res
 		}
-		return res.eval(ctx)
+		return evalStored(ctx, res)
 	}
-	if node.raw {
+	if raw {
 		switch r := res.(type) {
 		case fDict, fList:
-			return node.next.resolve(ctx, r)
+			return next.resolve(ctx, r)
 		}
 	}
-	ns = res.eval(ctx)
+	ns := evalStored(ctx, res)
 	if fErr, ok := ns.(fError); ok {
 		return fErr
 	}
-	return node.next.resolve(ctx, ns)
+	return next.resolve(ctx, ns)
+}
+
+// resolveOptional continues the resolution chain after a ?. step, which ends
+// it in none when its value is none, like when it is missing.
+func resolveOptional(ctx *context, next resolver, raw bool, res fExpr) fExpr {
+	switch res.(type) {
+	case fDict, fList:
+		return resolveNext(ctx, next, raw, res)
+	}
+	ns := evalStored(ctx, res)
+	switch v := ns.(type) {
+	case fError:
+		return v
+	case fNone:
+		return v
+	}
+	return next.resolve(ctx, ns)
+}
+
+// maxEvalDepth limits the nesting of stored values evaluated at once, as a
+// backstop for recursion that cycle detection can't see.
+const maxEvalDepth = 256
+
+// evalStored evaluates a value found during resolution, reporting values that
+// depend on themselves through references, formulas or subqueries instead of
+// recursing forever.
+func evalStored(ctx *context, value fExpr) fExpr {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice:
+		if v.Kind() != reflect.Ptr && v.Len() == 0 {
+			return value.eval(ctx)
+		}
+	default:
+		return value.eval(ctx)
+	}
+	key := evalKey{ctx.obj, v.Pointer()}
+	if ctx.evaluating == nil {
+		ctx.evaluating = map[evalKey]bool{}
+	}
+	if ctx.evaluating[key] {
+		return fError{"Reference cycle detected at " + ctx.obj.fetaPath()}
+	}
+	if len(ctx.evaluating) >= maxEvalDepth {
+		return fError{"Evaluation too deep at " + ctx.obj.fetaPath()}
+	}
+	ctx.evaluating[key] = true
+	defer delete(ctx.evaluating, key)
+	return value.eval(ctx)
+}
+
+type sliceRes struct {
+	start fExpr
+	stop  fExpr
+	step  fExpr
+	next  resolver
+	raw   bool
+}
+
+func (node *sliceRes) setNextAndRaw(next resolver, raw bool) {
+	node.next = next
+	node.raw = raw
+}
+
+func (node *sliceRes) resolve(ctx *context, ns fExpr) fExpr {
+	var bounds [3]*int
+	for i, expr := range []fExpr{node.start, node.stop, node.step} {
+		if expr == nil {
+			continue
+		}
+		value := expr.eval(ctx)
+		switch v := value.(type) {
+		case fError:
+			return v
+		case fNone:
+		case fNumber:
+			b := int(v)
+			bounds[i] = &b
+		default:
+			return fError{"Slices can only be bounded by numbers."}
+		}
+	}
+	var res fExpr
+	switch v := ns.(type) {
+	case fList:
+		indices, fErr := sliceIndices(len(v), bounds[0], bounds[1], bounds[2])
+		if fErr != nil {
+			return fErr
+		}
+		list := make(fList, len(indices))
+		for i, ii := range indices {
+			list[i] = v[ii]
+		}
+		res = list
+	case fString:
+		runes := []rune(string(v))
+		indices, fErr := sliceIndices(len(runes), bounds[0], bounds[1], bounds[2])
+		if fErr != nil {
+			return fErr
+		}
+		str := make([]rune, len(indices))
+		for i, ii := range indices {
+			str[i] = runes[ii]
+		}
+		res = fString(str)
+	default:
+		return fError{"Only lists and strings can be sliced."}
+	}
+	return resolveNext(ctx, node.next, node.raw, res)
+}
+
+// sliceIndices returns the positions selected by a Python style slice of a
+// sequence with the given length. Nil bounds take their default values.
+func sliceIndices(length int, start, stop, step *int) ([]int, fExpr) {
+	st := 1
+	if step != nil {
+		st = *step
+	}
+	if st == 0 {
+		return nil, fError{"Slice step can't be zero."}
+	}
+	lower, upper := 0, length
+	if st < 0 {
+		lower, upper = -1, length-1
+	}
+	clamp := func(bound *int, def int) int {
+		if bound == nil {
+			return def
+		}
+		b := *bound
+		if b < 0 {
+			b += length
+		}
+		if b < lower {
+			return lower
+		}
+		if b > upper {
+			return upper
+		}
+		return b
+	}
+	var indices []int
+	if st > 0 {
+		for i := clamp(start, lower); i < clamp(stop, upper); i += st {
+			indices = append(indices, i)
+		}
+	} else {
+		for i := clamp(start, upper); i > clamp(stop, lower); i += st {
+			indices = append(indices, i)
+		}
+	}
+	return indices, nil
 }
 
 type attribRes struct {
 	identifier string
+	optional   bool
 	next       resolver
 	raw        bool
 }
@@ -124,6 +318,9 @@ func (node *attribRes) setNextAndRaw(next resolver, raw bool) {
 }
 
 func (node *attribRes) eval(ctx *context) fExpr {
+	if value, exists := ctx.scope.lookup(node.identifier); exists {
+		return resolveNext(ctx, node.next, node.raw, value)
+	}
 	return node.resolve(ctx, ctx.meta)
 }
 
@@ -137,28 +334,25 @@ func (node *attribRes) resolve(ctx *context, ns fExpr) fExpr {
 		}
 		return node.next.resolve(ctx, ns)
 	}
+	if o, isObj := ns.(*object); isObj {
+		var fErr fExpr
+		if ctx, fErr = ctx.enter(o); fErr != nil {
+			return fErr
+		}
+		ns = ctx.meta
+	}
 	switch t := ns.(type) {
 	case fDict:
 		res, exists := t[node.identifier]
-		if exists {
-			if node.next == nil {
-				if node.raw {
-					return res
-				}
-				return res.eval(ctx)
-			}
-			if node.raw {
-				switch r := res.(type) {
-				case fDict, fList:
-					return node.next.resolve(ctx, r)
-				}
-			}
-			ns := res.eval(ctx)
-			if fErr, ok := ns.(fError); ok {
-				return fErr
-			}
-			return node.next.resolve(ctx, ns)
+		if !exists {
+			return fNone{}
+		}
+		if node.optional && node.next != nil {
+			return resolveOptional(ctx, node.next, node.raw, res)
 		}
+		return resolveNext(ctx, node.next, node.raw, res)
+	}
+	if node.optional {
 		return fNone{}
 	}
 	return fError{"Trying to access name in non-object type."}
@@ -177,6 +371,10 @@ const (
 	GREQ
 	LE
 	GR
+	IN
+	NIN
+	MATCH
+	NMATCH
 )
 
 func (node *compareNode) eval(ctx *context) fExpr {
@@ -188,7 +386,34 @@ func (node *compareNode) eval(ctx *context) fExpr {
 	if fErr, ok := right.(fError); ok {
 		return fErr
 	}
+	return node.compare(left, right)
+}
+
+func (node *compareNode) compare(left fExpr, right fExpr) fExpr {
+	switch node.op {
+	case IN, NIN:
+		res := contains(right, left)
+		if fErr, ok := res.(fError); ok {
+			return fErr
+		}
+		return fBool(res.(fBool) == (node.op == IN))
+	case MATCH, NMATCH:
+		res := match(left, right)
+		if fErr, ok := res.(fError); ok {
+			return fErr
+		}
+		return fBool(res.(fBool) == (node.op == MATCH))
+	}
 	switch l := left.(type) {
+	case *object:
+		r, same := right.(*object)
+		switch node.op {
+		case EQ:
+			return fBool(same && l == r)
+		case NEQ:
+			return fBool(!same || l != r)
+		}
+		return fError{"Objects are not orderable."}
 	case fNone:
 		_, same := right.(fNone)
 		switch node.op {
@@ -266,8 +491,114 @@ func (node *compareNode) eval(ctx *context) fExpr {
 		case GR:
 			return fBool(l > r)
 		}
+	case fDate:
+		r, same := right.(fDate)
+		if !same {
+			switch node.op {
+			case EQ:
+				return fBool(false)
+			case NEQ:
+				return fBool(true)
+			}
+			return fError{"Only '==' and '!=' is supported between different types."}
+		}
+		lt, rt := time.Time(l), time.Time(r)
+		switch node.op {
+		case EQ:
+			return fBool(lt.Equal(rt))
+		case NEQ:
+			return fBool(!lt.Equal(rt))
+		case LEEQ:
+			return fBool(!lt.After(rt))
+		case GREQ:
+			return fBool(!lt.Before(rt))
+		case LE:
+			return fBool(lt.Before(rt))
+		case GR:
+			return fBool(lt.After(rt))
+		}
+	case fDuration:
+		r, same := right.(fDuration)
+		if !same {
+			switch node.op {
+			case EQ:
+				return fBool(false)
+			case NEQ:
+				return fBool(true)
+			}
+			return fError{"Only '==' and '!=' is supported between different types."}
+		}
+		switch node.op {
+		case EQ:
+			return fBool(l == r)
+		case NEQ:
+			return fBool(l != r)
+		case LEEQ:
+			return fBool(l <= r)
+		case GREQ:
+			return fBool(l >= r)
+		case LE:
+			return fBool(l < r)
+		case GR:
+			return fBool(l > r)
+		}
+	}
+	return fError{"Only numbers, strings, dates and durations can be compared."}
+}
+
+// contains reports whether elm is an element of a list, a key of a dict or a
+// substring of a string. Nothing is contained in none.
+func contains(container fExpr, elm fExpr) fExpr {
+	switch c := container.(type) {
+	case fNone:
+		return fBool(false)
+	case fList:
+		for _, v := range c {
+			if equal(v, elm) {
+				return fBool(true)
+			}
+		}
+		return fBool(false)
+	case fDict:
+		k, isStr := elm.(fString)
+		if !isStr {
+			return fError{"Only strings can be looked up in dicts."}
+		}
+		_, exists := c[string(k)]
+		return fBool(exists)
+	case fString:
+		s, isStr := elm.(fString)
+		if !isStr {
+			return fError{"Only strings can be looked up in strings."}
+		}
+		return fBool(strings.Contains(string(c), string(s)))
 	}
-	return fError{"Only numbers and strings can be compared."}
+	return fError{"Membership can only be tested in lists, dicts and strings."}
+}
+
+var rexCache = map[string]*regexp.Regexp{}
+
+// match reports whether str matches the regular expression pattern. None
+// matches nothing.
+func match(str fExpr, pattern fExpr) fExpr {
+	if _, isNone := str.(fNone); isNone {
+		return fBool(false)
+	}
+	s, isStr := str.(fString)
+	p, isPatStr := pattern.(fString)
+	if !isStr || !isPatStr {
+		return fError{"Only strings can be matched against string patterns."}
+	}
+	rex, exists := rexCache[string(p)]
+	if !exists {
+		var err error
+		rex, err = regexp.Compile(string(p))
+		if err != nil {
+			return fError{"Invalid pattern: " + err.Error()}
+		}
+		rexCache[string(p)] = rex
+	}
+	return fBool(rex.MatchString(string(s)))
 }
 
 type addNode struct {
@@ -304,12 +635,47 @@ func (node *addNode) eval(ctx *context) fExpr {
 			return l + r
 		}
 		return fError{"Strings can not be subtracted from strings."}
+	case fList:
+		r, same := right.(fList)
+		if !same {
+			return fError{"Lists can only be added to lists."}
+		}
+		if node.op == '+' {
+			res := make(fList, 0, len(l)+len(r))
+			return append(append(res, l...), r...)
+		}
+		return fError{"Lists can not be subtracted from lists."}
+	case fDate:
+		switch r := right.(type) {
+		case fDuration:
+			if node.op == '+' {
+				return fDate(time.Time(l).Add(time.Duration(r)))
+			}
+			return fDate(time.Time(l).Add(-time.Duration(r)))
+		case fDate:
+			if node.op == '-' {
+				return dateDiff(l, r)
+			}
+			return fError{"Dates can not be added to dates."}
+		}
+		return fError{"Only durations can be added to dates."}
+	case fDuration:
+		switch r := right.(type) {
+		case fDuration:
+			return addDurations(l, r, node.op)
+		case fDate:
+			if node.op == '+' {
+				return fDate(time.Time(r).Add(time.Duration(l)))
+			}
+			return fError{"Dates can not be subtracted from durations."}
+		}
+		return fError{"Durations can only be added to durations and dates."}
 	}
-	return fError{"Only numbers and strings can be added."}
+	return fError{"Only numbers, strings, lists, dates and durations can be added."}
 }
 
 type multNode struct {
-	op    byte
+	op    string
 	left  fExpr
 	right fExpr
 }
@@ -325,18 +691,135 @@ func (node *multNode) eval(ctx *context) fExpr {
 	}
 	switch l := left.(type) {
 	case fNumber:
-		r, same := right.(fNumber)
-		if !same {
-			return fError{"Nubers can only be multiplied by numbers."}
+		switch r := right.(type) {
+		case fNumber:
+			if node.op == "*" {
+				return l * r
+			}
+			if r == 0 {
+				return fError{"Division by zero."}
+			}
+			switch node.op {
+			case "/":
+				return l / r
+			case "//":
+				return fNumber(math.Floor(float64(l / r)))
+			}
+			return l - r*fNumber(math.Floor(float64(l/r)))
+		case fString, fList:
+			if node.op == "*" {
+				return repeat(r, l)
+			}
+		case fDuration:
+			if node.op == "*" {
+				return durationOf(float64(l) * float64(r))
+			}
+		}
+		return fError{"Nubers can only be multiplied by numbers."}
+	case fDuration:
+		switch r := right.(type) {
+		case fNumber:
+			if node.op == "*" {
+				return durationOf(float64(l) * float64(r))
+			}
+			if node.op == "/" {
+				if r == 0 {
+					return fError{"Division by zero."}
+				}
+				return durationOf(float64(l) / float64(r))
+			}
+		case fDuration:
+			if node.op == "/" {
+				if r == 0 {
+					return fError{"Division by zero."}
+				}
+				return fNumber(float64(l) / float64(r))
+			}
 		}
-		if node.op == '*' {
-			return l * r
+		return fError{"Durations can only be multiplied by numbers and divided by numbers or durations."}
+	case fString, fList:
+		if r, isNum := right.(fNumber); isNum && node.op == "*" {
+			return repeat(l, r)
 		}
-		return l / r
+		return fError{"Strings and lists can only be multiplied by numbers."}
 	}
 	return fError{"Only numbers can be multiplied."}
 }
 
+// maxRepeatLen is the longest string or list a repetition may produce.
+const maxRepeatLen = 1 << 24
+
+func repeat(value fExpr, count fNumber) fExpr {
+	if count < 0 || float64(count) != math.Trunc(float64(count)) {
+		return fError{"Repeat count must be a non-negative integer."}
+	}
+	var size int
+	switch v := value.(type) {
+	case fString:
+		size = len(v)
+	case fList:
+		size = len(v)
+	}
+	if count > maxRepeatLen || (size > 0 && int(count) > maxRepeatLen/size) {
+		return fError{"Repetition is too long."}
+	}
+	n := int(count)
+	switch v := value.(type) {
+	case fString:
+		return fString(strings.Repeat(string(v), n))
+	case fList:
+		res := make(fList, 0, len(v)*n)
+		for i := 0; i < n; i++ {
+			res = append(res, v...)
+		}
+		return res
+	}
+	return fError{"Only strings and lists can be repeated."}
+}
+
+type powNode struct {
+	base     fExpr
+	exponent fExpr
+}
+
+func (node *powNode) eval(ctx *context) fExpr {
+	base := node.base.eval(ctx)
+	if fErr, ok := base.(fError); ok {
+		return fErr
+	}
+	exponent := node.exponent.eval(ctx)
+	if fErr, ok := exponent.(fError); ok {
+		return fErr
+	}
+	b, isNum := base.(fNumber)
+	e, isExpNum := exponent.(fNumber)
+	if !isNum || !isExpNum {
+		return fError{"Only numbers can be raised to a power."}
+	}
+	return fNumber(math.Pow(float64(b), float64(e)))
+}
+
+type negNode struct {
+	expr fExpr
+}
+
+func (node *negNode) eval(ctx *context) fExpr {
+	value := node.expr.eval(ctx)
+	if fErr, ok := value.(fError); ok {
+		return fErr
+	}
+	switch v := value.(type) {
+	case fNumber:
+		return -v
+	case fDuration:
+		if v == math.MinInt64 {
+			return fError{"Duration out of range."}
+		}
+		return -v
+	}
+	return fError{"Only numbers and durations can be negated."}
+}
+
 type andNode struct {
 	left  fExpr
 	right fExpr
@@ -347,11 +830,10 @@ func (node *andNode) eval(ctx *context) fExpr {
 	if fErr, ok := left.(fError); ok {
 		return fErr
 	}
-	right := node.right.eval(ctx)
-	if fErr, ok := right.(fError); ok {
-		return fErr
+	if !boolVal(left) {
+		return left
 	}
-	return boolVal(left) && boolVal(right)
+	return node.right.eval(ctx)
 }
 
 type orNode struct {
@@ -364,11 +846,75 @@ func (node *orNode) eval(ctx *context) fExpr {
 	if fErr, ok := left.(fError); ok {
 		return fErr
 	}
-	right := node.right.eval(ctx)
-	if fErr, ok := right.(fError); ok {
+	if boolVal(left) {
+		return left
+	}
+	return node.right.eval(ctx)
+}
+
+type coalesceNode struct {
+	left  fExpr
+	right fExpr
+}
+
+func (node *coalesceNode) eval(ctx *context) fExpr {
+	left := node.left.eval(ctx)
+	if _, ok := left.(fNone); ok {
+		return node.right.eval(ctx)
+	}
+	return left
+}
+
+type condNode struct {
+	cond fExpr
+	then fExpr
+	els  fExpr
+}
+
+func (node *condNode) eval(ctx *context) fExpr {
+	cond := node.cond.eval(ctx)
+	if fErr, ok := cond.(fError); ok {
 		return fErr
 	}
-	return boolVal(left) || boolVal(right)
+	if boolVal(cond) {
+		return node.then.eval(ctx)
+	}
+	return node.els.eval(ctx)
+}
+
+type binding struct {
+	name  string
+	value fExpr
+}
+
+type letNode struct {
+	bindings []binding
+	body     fExpr
+}
+
+func (node *letNode) eval(ctx *context) fExpr {
+	for _, bnd := range node.bindings {
+		value := bnd.value.eval(ctx)
+		if fErr, ok := value.(fError); ok {
+			return fErr
+		}
+		ctx = ctx.withScope(map[string]fExpr{bnd.name: value})
+	}
+	return node.body.eval(ctx)
+}
+
+type varNode struct {
+	name string
+}
+
+func (node *varNode) eval(ctx *context) fExpr {
+	if value, exists := ctx.scope.lookup(node.name); exists {
+		return value
+	}
+	if value, exists := ctx.vars[node.name]; exists {
+		return value
+	}
+	return fError{"Undefined variable: $" + node.name}
 }
 
 type compoundNode struct {
@@ -384,13 +930,18 @@ type notNode struct {
 }
 
 func (node *notNode) eval(ctx *context) fExpr {
-	return !boolVal(node.expr.eval(ctx))
+	value := node.expr.eval(ctx)
+	if fErr, ok := value.(fError); ok {
+		return fErr
+	}
+	return !boolVal(value)
 }
 
 type queryNode struct {
 	sel   selector
 	multi bool
 	tail  bool
+	src   string
 }
 
 func (node *queryNode) eval(ctx *context) fExpr {
@@ -0,0 +1,64 @@
+package feta
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// formatMeta returns the canonical form of the stored metadata of an object,
+// or nil if the object has none.
+func formatMeta(st storage, o *object) (data []byte, formatted []byte, err error) {
+	data, err = st.read(o)
+	if err != nil || data == nil {
+		return nil, nil, err
+	}
+	path := st.location(o)
+	dict, comments, err := parseSidecar(path, data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Couldn't parse meta file '%s': %v", path, err)
+	}
+	formatted = marshalMeta(dict, comments)
+	if _, _, err := parseSidecar(path, formatted); err != nil {
+		return nil, nil, fmt.Errorf("Couldn't format meta file '%s': %v", path, err)
+	}
+	return data, formatted, nil
+}
+
+// Format rewrites the metadata of the selected objects in the canonical style,
+// or with check set only reports the objects that aren't formatted. It returns
+// the list of those objects and whether all were formatted already.
+func Format(query string, check bool, workDir string) ([]byte, bool, error) {
+	objs, err := selectObjects(query, workDir)
+	if err != nil {
+		return nil, false, err
+	}
+	if !check {
+		unlock, err := lockSite()
+		if err != nil {
+			return nil, false, err
+		}
+		defer unlock()
+	}
+	st, err := getStorage()
+	if err != nil {
+		return nil, false, err
+	}
+	res := fList{}
+	for _, o := range objs {
+		data, formatted, err := formatMeta(st, o)
+		if err != nil {
+			return nil, false, fmt.Errorf("%v at %s", err, o.fetaPath())
+		}
+		if data == nil || bytes.Equal(data, formatted) {
+			continue
+		}
+		if !check {
+			if err := st.write(o, formatted); err != nil {
+				return nil, false, fmt.Errorf("Couldn't write metadata of %s: %v", o.fetaPath(), err)
+			}
+			o.meta = nil
+		}
+		res = append(res, o)
+	}
+	return marshal(res, !Flags.UglyJSON), len(res) == 0, nil
+}
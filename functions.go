@@ -0,0 +1,448 @@
+package feta
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type function func(ctx *context, args []fExpr) fExpr
+
+var functions = map[string]function{
+	"startswith":  startsWithFn,
+	"endswith":    endsWithFn,
+	"keys":        keysFn,
+	"values":      valuesFn,
+	"items":       itemsFn,
+	"map":         mapFn,
+	"filter":      filterFn,
+	"reduce":      reduceFn,
+	"any":         anyFn,
+	"all":         allFn,
+	"sort_by":     sortByFn,
+	"unique":      uniqueFn,
+	"number":      numberFn,
+	"string":      stringFn,
+	"referrers":   referrersFn,
+	"tagged":      taggedFn,
+	"now":         nowFn,
+	"date":        dateFn,
+	"duration":    durationFn,
+	"format_date": formatDateFn,
+}
+
+type lambdaNode struct {
+	params []string
+	body   fExpr
+}
+
+func (node *lambdaNode) eval(ctx *context) fExpr {
+	return fLambda{params: node.params, body: node.body, scope: ctx.scope}
+}
+
+type callNode struct {
+	name string
+	args []fExpr
+}
+
+func (node *callNode) eval(ctx *context) fExpr {
+	fn, exists := functions[node.name]
+	if !exists {
+		return fError{"Unknown function: " + node.name}
+	}
+	args := make([]fExpr, len(node.args))
+	for i, arg := range node.args {
+		args[i] = arg.eval(ctx)
+		if fErr, ok := args[i].(fError); ok {
+			return fErr
+		}
+	}
+	return fn(ctx, args)
+}
+
+func checkArgs(name string, args []fExpr, count int) fExpr {
+	if len(args) != count {
+		return fError{name + "() takes exactly " + strconv.Itoa(count) + " argument(s)."}
+	}
+	return nil
+}
+
+func stringArgs(name string, args []fExpr) ([]string, fExpr) {
+	strs := make([]string, len(args))
+	for i, arg := range args {
+		s, isStr := arg.(fString)
+		if !isStr {
+			return nil, fError{name + "() only accepts strings."}
+		}
+		strs[i] = string(s)
+	}
+	return strs, nil
+}
+
+func startsWithFn(ctx *context, args []fExpr) fExpr {
+	if fErr := checkArgs("startswith", args, 2); fErr != nil {
+		return fErr
+	}
+	strs, fErr := stringArgs("startswith", args)
+	if fErr != nil {
+		return fErr
+	}
+	return fBool(strings.HasPrefix(strs[0], strs[1]))
+}
+
+func endsWithFn(ctx *context, args []fExpr) fExpr {
+	if fErr := checkArgs("endswith", args, 2); fErr != nil {
+		return fErr
+	}
+	strs, fErr := stringArgs("endswith", args)
+	if fErr != nil {
+		return fErr
+	}
+	return fBool(strings.HasSuffix(strs[0], strs[1]))
+}
+
+func dictArg(name string, args []fExpr) (fDict, fExpr) {
+	if fErr := checkArgs(name, args, 1); fErr != nil {
+		return nil, fErr
+	}
+	d, isDict := args[0].(fDict)
+	if !isDict {
+		return nil, fError{name + "() only accepts dicts."}
+	}
+	return d, nil
+}
+
+func keysFn(ctx *context, args []fExpr) fExpr {
+	d, fErr := dictArg("keys", args)
+	if fErr != nil {
+		return fErr
+	}
+	res := fList{}
+	for _, k := range sortedKeys(d) {
+		res = append(res, fString(k))
+	}
+	return res
+}
+
+func valuesFn(ctx *context, args []fExpr) fExpr {
+	d, fErr := dictArg("values", args)
+	if fErr != nil {
+		return fErr
+	}
+	res := fList{}
+	for _, k := range sortedKeys(d) {
+		res = append(res, d[k])
+	}
+	return res
+}
+
+func itemsFn(ctx *context, args []fExpr) fExpr {
+	d, fErr := dictArg("items", args)
+	if fErr != nil {
+		return fErr
+	}
+	res := fList{}
+	for _, k := range sortedKeys(d) {
+		res = append(res, fList{fString(k), d[k]})
+	}
+	return res
+}
+
+// listArg returns the list argument at index i. None is treated as an empty
+// list, so list functions can be applied to missing attributes.
+func listArg(name string, args []fExpr, i int) (fList, fExpr) {
+	switch l := args[i].(type) {
+	case fList:
+		return l, nil
+	case fNone:
+		return fList{}, nil
+	}
+	return nil, fError{name + "() only accepts lists."}
+}
+
+func lambdaArg(name string, args []fExpr, i int) (fLambda, fExpr) {
+	l, isLambda := args[i].(fLambda)
+	if !isLambda {
+		return fLambda{}, fError{name + "() expects a lambda as argument " + strconv.Itoa(i+1) + "."}
+	}
+	return l, nil
+}
+
+func listAndLambdaArgs(name string, args []fExpr) (fList, fLambda, fExpr) {
+	if fErr := checkArgs(name, args, 2); fErr != nil {
+		return nil, fLambda{}, fErr
+	}
+	list, fErr := listArg(name, args, 0)
+	if fErr != nil {
+		return nil, fLambda{}, fErr
+	}
+	fn, fErr := lambdaArg(name, args, 1)
+	if fErr != nil {
+		return nil, fLambda{}, fErr
+	}
+	return list, fn, nil
+}
+
+func mapFn(ctx *context, args []fExpr) fExpr {
+	list, fn, fErr := listAndLambdaArgs("map", args)
+	if fErr != nil {
+		return fErr
+	}
+	res := make(fList, len(list))
+	for i, elm := range list {
+		res[i] = fn.call(ctx, elm)
+		if fErr, ok := res[i].(fError); ok {
+			return fErr
+		}
+	}
+	return res
+}
+
+func filterFn(ctx *context, args []fExpr) fExpr {
+	list, fn, fErr := listAndLambdaArgs("filter", args)
+	if fErr != nil {
+		return fErr
+	}
+	res := fList{}
+	for _, elm := range list {
+		keep := fn.call(ctx, elm)
+		if fErr, ok := keep.(fError); ok {
+			return fErr
+		}
+		if boolVal(keep) {
+			res = append(res, elm)
+		}
+	}
+	return res
+}
+
+func reduceFn(ctx *context, args []fExpr) fExpr {
+	if len(args) != 2 && len(args) != 3 {
+		return fError{"reduce() takes 2 or 3 arguments."}
+	}
+	list, fErr := listArg("reduce", args, 0)
+	if fErr != nil {
+		return fErr
+	}
+	fn, fErr := lambdaArg("reduce", args, 1)
+	if fErr != nil {
+		return fErr
+	}
+	var acc fExpr
+	if len(args) == 3 {
+		acc = args[2]
+	} else {
+		if len(list) == 0 {
+			return fError{"reduce() of empty list with no initial value."}
+		}
+		acc, list = list[0], list[1:]
+	}
+	for _, elm := range list {
+		acc = fn.call(ctx, acc, elm)
+		if fErr, ok := acc.(fError); ok {
+			return fErr
+		}
+	}
+	return acc
+}
+
+// truthCount returns how many elements of the list are true, either by
+// themselves or mapped through the optional lambda argument.
+func truthCount(ctx *context, name string, args []fExpr) (int, int, fExpr) {
+	if len(args) != 1 && len(args) != 2 {
+		return 0, 0, fError{name + "() takes 1 or 2 arguments."}
+	}
+	list, fErr := listArg(name, args, 0)
+	if fErr != nil {
+		return 0, 0, fErr
+	}
+	var fn fLambda
+	if len(args) == 2 {
+		fn, fErr = lambdaArg(name, args, 1)
+		if fErr != nil {
+			return 0, 0, fErr
+		}
+	}
+	count := 0
+	for _, elm := range list {
+		if len(args) == 2 {
+			elm = fn.call(ctx, elm)
+			if fErr, ok := elm.(fError); ok {
+				return 0, 0, fErr
+			}
+		}
+		if boolVal(elm) {
+			count++
+		}
+	}
+	return count, len(list), nil
+}
+
+func anyFn(ctx *context, args []fExpr) fExpr {
+	count, _, fErr := truthCount(ctx, "any", args)
+	if fErr != nil {
+		return fErr
+	}
+	return fBool(count > 0)
+}
+
+func allFn(ctx *context, args []fExpr) fExpr {
+	count, length, fErr := truthCount(ctx, "all", args)
+	if fErr != nil {
+		return fErr
+	}
+	return fBool(count == length)
+}
+
+func sortByFn(ctx *context, args []fExpr) fExpr {
+	list, fn, fErr := listAndLambdaArgs("sort_by", args)
+	if fErr != nil {
+		return fErr
+	}
+	keys := make(fList, len(list))
+	for i, elm := range list {
+		keys[i] = fn.call(ctx, elm)
+		if fErr, ok := keys[i].(fError); ok {
+			return fErr
+		}
+	}
+	indices := make([]int, len(list))
+	for i := range indices {
+		indices[i] = i
+	}
+	var sortErr fExpr
+	sort.SliceStable(indices, func(a, b int) bool {
+		less := (&compareNode{op: LE}).compare(keys[indices[a]], keys[indices[b]])
+		if fErr, ok := less.(fError); ok {
+			sortErr = fErr
+			return false
+		}
+		return bool(less.(fBool))
+	})
+	if sortErr != nil {
+		return sortErr
+	}
+	res := make(fList, len(list))
+	for i, ii := range indices {
+		res[i] = list[ii]
+	}
+	return res
+}
+
+func uniqueFn(ctx *context, args []fExpr) fExpr {
+	if fErr := checkArgs("unique", args, 1); fErr != nil {
+		return fErr
+	}
+	list, fErr := listArg("unique", args, 0)
+	if fErr != nil {
+		return fErr
+	}
+	res := fList{}
+	for _, elm := range list {
+		if !bool(contains(res, elm).(fBool)) {
+			res = append(res, elm)
+		}
+	}
+	return res
+}
+
+func numberFn(ctx *context, args []fExpr) fExpr {
+	if fErr := checkArgs("number", args, 1); fErr != nil {
+		return fErr
+	}
+	switch v := args[0].(type) {
+	case fNumber:
+		return v
+	case fBool:
+		if v {
+			return fNumber(1)
+		}
+		return fNumber(0)
+	case fString:
+		n, err := strconv.ParseFloat(strings.TrimSpace(string(v)), 64)
+		if err != nil {
+			return fError{"Couldn't convert '" + string(v) + "' to number."}
+		}
+		return fNumber(n)
+	case fDate:
+		return fNumber(float64(time.Time(v).UnixNano()) / 1e9)
+	case fDuration:
+		return fNumber(time.Duration(v).Seconds())
+	}
+	return fError{"number() only accepts numbers, booleans, strings, dates and durations."}
+}
+
+func stringFn(ctx *context, args []fExpr) fExpr {
+	if fErr := checkArgs("string", args, 1); fErr != nil {
+		return fErr
+	}
+	switch v := args[0].(type) {
+	case fString:
+		return v
+	case fDate:
+		return fString(formatDate(v))
+	}
+	return fString(inline(args[0]))
+}
+
+// referrersFn lists the objects of the site with metadata referring to the
+// context object, or to the object given as argument.
+func referrersFn(ctx *context, args []fExpr) fExpr {
+	target := ctx.obj
+	switch len(args) {
+	case 0:
+	case 1:
+		o, isObj := args[0].(*object)
+		if !isObj {
+			return fError{"referrers() only accepts objects."}
+		}
+		target = o
+	default:
+		return fError{"referrers() takes at most 1 argument."}
+	}
+	objs := append(fList{site}, walk(&context{obj: site}, nil)...)
+	res := fList{}
+	for _, o := range objs {
+		if fErr, ok := o.(fError); ok {
+			return fErr
+		}
+		holder := o.(*object)
+		meta, err := holder.getMeta()
+		if err != nil {
+			return fError{err.Error() + " at " + holder.fetaPath()}
+		}
+		if refersTo(holder, meta, target) {
+			res = append(res, holder)
+		}
+	}
+	return res
+}
+
+// refersTo reports whether a metadata value of holder contains an object
+// reference or an absolute feta path pointing to target.
+func refersTo(holder *object, value fExpr, target *object) bool {
+	switch v := value.(type) {
+	case fDict:
+		for _, elm := range v {
+			if refersTo(holder, elm, target) {
+				return true
+			}
+		}
+	case fList:
+		for _, elm := range v {
+			if refersTo(holder, elm, target) {
+				return true
+			}
+		}
+	case fRef:
+		o, isObj := v.eval(&context{obj: holder}).(*object)
+		return isObj && o == target
+	case *object:
+		return v == target
+	case fString:
+		path := target.fetaPath()
+		return string(v) == path || string(v)+"/" == path
+	}
+	return false
+}
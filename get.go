@@ -13,6 +13,51 @@ func Get(query string, workDir string) ([]byte, error) {
 	if err != nil {
 		return nil, fmt.Errorf("Couldn't parse query '%s': %v", query, err)
 	}
-	res := ast.(fExpr).eval(&context{obj: workDirObj})
+	res := ast.(fExpr).eval(&context{obj: workDirObj, vars: cliVars()})
 	return marshal(res.(fNode), !Flags.UglyJSON), nil
 }
+
+// cliVars returns the variables given on the command line.
+func cliVars() map[string]fExpr {
+	vars := map[string]fExpr{}
+	for k, v := range Flags.Vars {
+		vars[k] = fString(v)
+	}
+	return vars
+}
+
+// selectObjects returns the objects selected by a query without a tail.
+func selectObjects(query string, workDir string) ([]*object, error) {
+	return selectObjectsIn(query, workDir, cliVars())
+}
+
+// selectObjectsIn returns the objects selected by a query without a tail,
+// with the given variables.
+func selectObjectsIn(query string, workDir string, vars map[string]fExpr) ([]*object, error) {
+	workDirObj, err := getObject(workDir)
+	if err != nil {
+		return nil, fmt.Errorf("Couldn't get object for workdir '%s': %v", workDir, err)
+	}
+	ast, err := Parse(query, []byte(query))
+	if err != nil {
+		return nil, fmt.Errorf("Couldn't parse query '%s': %v", query, err)
+	}
+	if ast.(*queryNode).tail {
+		return nil, fmt.Errorf("Query '%s' must select objects, not values", query)
+	}
+	res := ast.(fExpr).eval(&context{obj: workDirObj, vars: vars})
+	list, isList := res.(fList)
+	if !isList {
+		list = fList{res}
+	}
+	objs := make([]*object, 0, len(list))
+	for _, elm := range list {
+		switch v := elm.(type) {
+		case *object:
+			objs = append(objs, v)
+		case fError:
+			return nil, v
+		}
+	}
+	return objs, nil
+}
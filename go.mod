@@ -2,9 +2,12 @@ module github.com/gadfly16/feta
 
 go 1.17
 
-require github.com/tidwall/pretty v1.2.0
-
 require (
-	github.com/maruel/natural v1.0.0 // indirect
-	github.com/otiai10/copy v1.7.0 // indirect
+	github.com/maruel/natural v1.0.0
+	github.com/otiai10/copy v1.7.0
+	github.com/tidwall/pretty v1.2.0
+	go.etcd.io/bbolt v1.3.9
+	gopkg.in/yaml.v3 v3.0.1
 )
+
+require golang.org/x/sys v0.10.0 // indirect
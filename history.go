@@ -0,0 +1,269 @@
+package feta
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"time"
+)
+
+// A historyRecord describes the change of one attribute made by a write
+// through feta. Old and New hold the raw values in feta syntax, and are nil
+// when the attribute didn't exist. The records of a single write share the
+// same change number.
+type historyRecord struct {
+	Change int
+	Time   string
+	User   string
+	Obj    string
+	Path   string
+	Old    *string
+	New    *string
+}
+
+// history caches the history log of the site.
+var history []historyRecord
+
+func historyPath() string {
+	return site.sysPath() + ".feta/history"
+}
+
+// readHistory returns the records of the history log of the site, which is
+// stored as one JSON record per line.
+func readHistory() ([]historyRecord, error) {
+	if history != nil {
+		return history, nil
+	}
+	records := []historyRecord{}
+	f, err := os.Open(historyPath())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			history = records
+			return history, nil
+		}
+		return nil, fmt.Errorf("Couldn't open history: %v", err)
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 64*1024*1024)
+	for scanner.Scan() {
+		var rec historyRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return nil, fmt.Errorf("Couldn't decode history record: %v", err)
+		}
+		records = append(records, rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("Couldn't read history: %v", err)
+	}
+	history = records
+	return history, nil
+}
+
+func userName() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+// appendHistory stamps the records as a new change and appends them to the
+// history log.
+func appendHistory(records []historyRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+	past, err := readHistory()
+	if err != nil {
+		return err
+	}
+	change := 1
+	if len(past) != 0 {
+		change = past[len(past)-1].Change + 1
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	name := userName()
+	var lines []byte
+	for i := range records {
+		records[i].Change = change
+		records[i].Time = now
+		records[i].User = name
+		js, err := json.Marshal(records[i])
+		if err != nil {
+			return fmt.Errorf("Couldn't encode history record: %v", err)
+		}
+		lines = append(append(lines, js...), '\n')
+	}
+	f, err := os.OpenFile(historyPath(), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("Couldn't open history: %v", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(lines); err != nil {
+		return fmt.Errorf("Couldn't write history: %v", err)
+	}
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("Couldn't write history: %v", err)
+	}
+	history = append(past, records...)
+	return nil
+}
+
+func rawText(value fExpr, exists bool) *string {
+	if !exists {
+		return nil
+	}
+	s := inline(value)
+	return &s
+}
+
+func sameText(a *string, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// diffMeta returns a record for every attribute that differs between two raw
+// sidecar dicts. Dicts present on both sides are compared attribute by
+// attribute.
+func diffMeta(obj string, prefix string, old fDict, new fDict) []historyRecord {
+	keys := fDict{}
+	for k, v := range old {
+		keys[k] = v
+	}
+	for k, v := range new {
+		keys[k] = v
+	}
+	records := []historyRecord{}
+	for _, k := range sortedKeys(keys) {
+		o, inOld := old[k]
+		n, inNew := new[k]
+		oDict, oIsDict := o.(fDict)
+		nDict, nIsDict := n.(fDict)
+		if oIsDict && nIsDict {
+			records = append(records, diffMeta(obj, joinPath(prefix, k), oDict, nDict)...)
+			continue
+		}
+		oText, nText := rawText(o, inOld), rawText(n, inNew)
+		if !sameText(oText, nText) {
+			records = append(records, historyRecord{Obj: obj, Path: joinPath(prefix, k), Old: oText, New: nText})
+		}
+	}
+	return records
+}
+
+func parseText(text *string) (fExpr, error) {
+	if text == nil {
+		return fNone{}, nil
+	}
+	expr, err := Parse(*text, []byte(*text), Entrypoint("Expression"))
+	if err != nil {
+		return nil, fmt.Errorf("Couldn't parse value '%s': %v", *text, err)
+	}
+	return expr.(fExpr), nil
+}
+
+// values parses the old and new values of the record, with none standing
+// for missing attributes.
+func (rec historyRecord) values() (fExpr, fExpr, error) {
+	old, err := parseText(rec.Old)
+	if err != nil {
+		return nil, nil, err
+	}
+	new, err := parseText(rec.New)
+	if err != nil {
+		return nil, nil, err
+	}
+	return old, new, nil
+}
+
+// History lists the recorded changes of the selected objects.
+func History(query string, workDir string) ([]byte, error) {
+	objs, err := selectObjects(query, workDir)
+	if err != nil {
+		return nil, err
+	}
+	selected := map[string]bool{}
+	for _, o := range objs {
+		selected[o.fetaPath()] = true
+	}
+	records, err := readHistory()
+	if err != nil {
+		return nil, err
+	}
+	res := fList{}
+	for _, rec := range records {
+		if !selected[rec.Obj] {
+			continue
+		}
+		old, new, err := rec.values()
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, fDict{
+			"Change": fNumber(rec.Change),
+			"Time":   fString(rec.Time),
+			"User":   fString(rec.User),
+			"Obj":    fString(rec.Obj),
+			"Path":   fString(rec.Path),
+			"Old":    old,
+			"New":    new,
+		})
+	}
+	return marshal(res, !Flags.UglyJSON), nil
+}
+
+// Undo reverts the attributes changed by a change. It fails if any of them
+// was changed again since.
+func Undo(change int) ([]byte, error) {
+	records, err := readHistory()
+	if err != nil {
+		return nil, err
+	}
+	byObj := map[*object][]historyRecord{}
+	objs := []*object{}
+	for _, rec := range records {
+		if rec.Change != change {
+			continue
+		}
+		o, err := site.lookup(rec.Obj)
+		if err != nil {
+			return nil, fmt.Errorf("Couldn't find %s: %v", rec.Obj, err)
+		}
+		if _, exists := byObj[o]; !exists {
+			objs = append(objs, o)
+		}
+		byObj[o] = append(byObj[o], rec)
+	}
+	if len(objs) == 0 {
+		return nil, fmt.Errorf("Unknown change: %d", change)
+	}
+	res, err := modify(objs, func(o *object, meta fDict) error {
+		for _, rec := range byObj[o] {
+			current, exists := getPath(meta, rec.Path)
+			if !sameText(rawText(current, exists), rec.New) {
+				return fmt.Errorf("Attribute '%s' was changed after change %d", rec.Path, change)
+			}
+			if rec.Old == nil {
+				unsetPath(meta, rec.Path)
+				continue
+			}
+			old, err := parseText(rec.Old)
+			if err != nil {
+				return err
+			}
+			if err := setPath(meta, rec.Path, old); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return marshal(res, !Flags.UglyJSON), nil
+}
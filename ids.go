@@ -0,0 +1,161 @@
+package feta
+
+import (
+	"crypto/rand"
+	"fmt"
+	"strings"
+)
+
+const idKey = "uuid"
+
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+// id returns the persistent id of the object, or an empty string if it has
+// none.
+func (o *object) id() (string, error) {
+	meta, err := o.getMeta()
+	if err != nil {
+		return "", err
+	}
+	id, isStr := meta[idKey].(fString)
+	if !isStr {
+		return "", nil
+	}
+	return string(id), nil
+}
+
+// assignID returns the id of the object, storing a new one in its sidecar if
+// it has none yet. The new id is written under the lock of the site and
+// recorded in its history like any other change.
+func (o *object) assignID() (string, error) {
+	meta, err := o.readMeta()
+	if err != nil {
+		return "", err
+	}
+	if id, isStr := meta[idKey].(fString); isStr {
+		return string(id), nil
+	}
+	var id string
+	_, err = modify([]*object{o}, func(o *object, meta fDict) error {
+		if existing, isStr := meta[idKey].(fString); isStr {
+			id = string(existing)
+			return nil
+		}
+		fresh, err := newID()
+		if err != nil {
+			return fmt.Errorf("Couldn't generate id: %v", err)
+		}
+		id = fresh
+		meta[idKey] = fString(id)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// siteObjects returns every object of the site, starting with the site
+// itself.
+func siteObjects() ([]*object, error) {
+	objs := []*object{site}
+	for _, o := range walk(&context{obj: site}, nil) {
+		if fErr, ok := o.(fError); ok {
+			return nil, fErr
+		}
+		objs = append(objs, o.(*object))
+	}
+	return objs, nil
+}
+
+// idIndex maps the ids found in the site to the objects holding them.
+func idIndex() (map[string][]*object, error) {
+	objs, err := siteObjects()
+	if err != nil {
+		return nil, err
+	}
+	index := map[string][]*object{}
+	for _, o := range objs {
+		id, err := o.id()
+		if err != nil {
+			return nil, fmt.Errorf("%v at %s", err, o.fetaPath())
+		}
+		if id != "" {
+			index[id] = append(index[id], o)
+		}
+	}
+	return index, nil
+}
+
+// findByID returns the single object holding id. Ids shared by several
+// objects, usually the result of copying files with their sidecars, are
+// reported as errors.
+func findByID(id string) (*object, error) {
+	index, err := idIndex()
+	if err != nil {
+		return nil, err
+	}
+	objs := index[id]
+	switch len(objs) {
+	case 0:
+		return nil, fmt.Errorf("No object with id: %s", id)
+	case 1:
+		return objs[0], nil
+	}
+	paths := make([]string, len(objs))
+	for i, o := range objs {
+		paths[i] = o.fetaPath()
+	}
+	return nil, fmt.Errorf("Duplicate object id %s: %s", id, strings.Join(paths, ", "))
+}
+
+func ID(path string, workDir string) ([]byte, error) {
+	workDirObj, err := getObject(workDir)
+	if err != nil {
+		return nil, fmt.Errorf("Couldn't get object for workdir '%s': %v", workDir, err)
+	}
+	o, err := workDirObj.lookup(path)
+	if err != nil {
+		return nil, fmt.Errorf("Couldn't find object '%s': %v", path, err)
+	}
+	id, err := o.assignID()
+	if err != nil {
+		return nil, fmt.Errorf("Couldn't assign id to '%s': %v", o.fetaPath(), err)
+	}
+	return marshal(fString(id), !Flags.UglyJSON), nil
+}
+
+func Resolve(id string) ([]byte, error) {
+	o, err := findByID(strings.ToLower(id))
+	if err != nil {
+		return nil, err
+	}
+	return marshal(o, !Flags.UglyJSON), nil
+}
+
+// DuplicateIDs lists the ids shared by more than one object of the site.
+func DuplicateIDs() ([]byte, error) {
+	index, err := idIndex()
+	if err != nil {
+		return nil, err
+	}
+	res := fDict{}
+	for id, objs := range index {
+		if len(objs) > 1 {
+			list := make(fList, len(objs))
+			for i, o := range objs {
+				list[i] = o
+			}
+			res[id] = list
+		}
+	}
+	return marshal(res, !Flags.UglyJSON), nil
+}
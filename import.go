@@ -0,0 +1,298 @@
+package feta
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ImportOptions control how records are mapped to objects and merged into
+// their sidecars.
+type ImportOptions struct {
+	Format  string // json, yaml or csv, taken from the file extension if empty
+	Key     string // field of the records holding the path of their object, never imported
+	Query   string // query selecting the objects of a record, with its fields as $variables
+	Replace bool   // replace the sidecars instead of merging the records into them
+	DryRun  bool   // report the changes without writing them
+	Archive bool   // restore an archive written by Export
+}
+
+// toExpr converts a decoded JSON or YAML value to a feta value.
+func toExpr(value interface{}) (fExpr, error) {
+	switch v := value.(type) {
+	case nil:
+		return fNone{}, nil
+	case bool:
+		return fBool(v), nil
+	case string:
+		return fString(v), nil
+	case float64:
+		return fNumber(v), nil
+	case int:
+		return fNumber(v), nil
+	case int64:
+		return fNumber(v), nil
+	case uint64:
+		return fNumber(v), nil
+	case time.Time:
+		return fDate(v), nil
+	case []interface{}:
+		list := make(fList, len(v))
+		for i, elm := range v {
+			e, err := toExpr(elm)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = e
+		}
+		return list, nil
+	case map[string]interface{}:
+		dict := make(fDict, len(v))
+		for k, elm := range v {
+			e, err := toExpr(elm)
+			if err != nil {
+				return nil, err
+			}
+			dict[k] = e
+		}
+		return dict, nil
+	}
+	return nil, fmt.Errorf("Unsupported value: %v", value)
+}
+
+// numberRex matches the number literals of the grammar, with an optional
+// minus sign.
+var numberRex = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+// csvValue converts a CSV cell to a number or bool where it looks like one.
+// Cells like NaN, Inf or 0010 stay strings, as they don't read back as the
+// same number.
+func csvValue(cell string) fExpr {
+	switch cell {
+	case "true":
+		return fBool(true)
+	case "false":
+		return fBool(false)
+	}
+	if numberRex.MatchString(cell) {
+		if n, err := strconv.ParseFloat(cell, 64); err == nil && !math.IsInf(n, 0) {
+			return fNumber(n)
+		}
+	}
+	return fString(cell)
+}
+
+// readRecords decodes the records of a file. JSON and YAML files hold either
+// a list of records or a dict of records by object path, while CSV files
+// hold a header row of attribute paths.
+func readRecords(path string, format string, key string) ([]fDict, error) {
+	if format == "" {
+		format = strings.TrimPrefix(filepath.Ext(path), ".")
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Couldn't read import file: %v", err)
+	}
+	var decoded interface{}
+	switch format {
+	case "json":
+		err = json.Unmarshal(data, &decoded)
+	case "yaml", "yml":
+		err = yaml.Unmarshal(data, &decoded)
+	case "csv":
+		return readCSV(data)
+	default:
+		return nil, fmt.Errorf("Unknown import format '%s'", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Couldn't decode import file: %v", err)
+	}
+	var items fList
+	switch v := decoded.(type) {
+	case []interface{}:
+		for _, elm := range v {
+			e, err := toExpr(elm)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, e)
+		}
+	case map[string]interface{}:
+		byPath := fDict{}
+		for p, elm := range v {
+			e, err := toExpr(elm)
+			if err != nil {
+				return nil, err
+			}
+			if rec, isDict := e.(fDict); isDict {
+				rec[key] = fString(p)
+			}
+			byPath[p] = e
+		}
+		for _, p := range sortedKeys(byPath) {
+			items = append(items, byPath[p])
+		}
+	default:
+		return nil, fmt.Errorf("Import file must hold a list or dict of records")
+	}
+	records := make([]fDict, len(items))
+	for i, item := range items {
+		rec, isDict := item.(fDict)
+		if !isDict {
+			return nil, fmt.Errorf("Record %d isn't a dict", i+1)
+		}
+		records[i] = rec
+	}
+	return records, nil
+}
+
+func readCSV(data []byte) ([]fDict, error) {
+	rows, err := csv.NewReader(strings.NewReader(string(data))).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("Couldn't decode import file: %v", err)
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	header := rows[0]
+	for _, col := range header {
+		if _, err := splitPath(col); err != nil {
+			return nil, fmt.Errorf("Column '%s' can't be used as an attribute path", col)
+		}
+	}
+	records := []fDict{}
+	for _, row := range rows[1:] {
+		rec := fDict{}
+		for i, cell := range row {
+			if cell == "" {
+				continue
+			}
+			if err := setPath(rec, header[i], csvValue(cell)); err != nil {
+				return nil, err
+			}
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
+
+// Import merges the records of a file into the sidecars of the objects they
+// map to, either by the path in their key field, resolved like paths given on
+// the command line, or by a query. Records matching no object are reported.
+func Import(path string, opts ImportOptions, workDir string) ([]byte, error) {
+	if opts.Archive {
+		return importArchive(path, opts.DryRun)
+	}
+	if opts.Key == "" {
+		opts.Key = "path"
+	}
+	records, err := readRecords(path, opts.Format, opts.Key)
+	if err != nil {
+		return nil, err
+	}
+	workDirObj, err := getObject(workDir)
+	if err != nil {
+		return nil, fmt.Errorf("Couldn't get object for workdir '%s': %v", workDir, err)
+	}
+	objs := []*object{}
+	byObj := map[*object][]fDict{}
+	unmatched := fList{}
+	for i, rec := range records {
+		var matched []*object
+		if opts.Query != "" {
+			vars := cliVars()
+			for k, v := range rec {
+				vars[k] = v
+			}
+			matched, err = selectObjectsIn(opts.Query, workDir, vars)
+			if err != nil {
+				return nil, fmt.Errorf("%v for record %d", err, i+1)
+			}
+			delete(rec, opts.Key)
+		} else {
+			p, isStr := rec[opts.Key].(fString)
+			if !isStr {
+				unmatched = append(unmatched, fDict{"Record": fNumber(i + 1)})
+				continue
+			}
+			delete(rec, opts.Key)
+			o, err := workDirObj.lookup(string(p))
+			if err != nil {
+				unmatched = append(unmatched, fDict{"Record": fNumber(i + 1), "Path": p})
+				continue
+			}
+			matched = []*object{o}
+		}
+		if len(matched) == 0 {
+			unmatched = append(unmatched, fDict{"Record": fNumber(i + 1)})
+		}
+		for _, o := range matched {
+			if _, exists := byObj[o]; !exists {
+				objs = append(objs, o)
+			}
+			byObj[o] = append(byObj[o], rec)
+		}
+	}
+	change := func(o *object, meta fDict) error {
+		if opts.Replace {
+			for k := range meta {
+				if k != idKey {
+					delete(meta, k)
+				}
+			}
+		}
+		for _, rec := range byObj[o] {
+			mergeDict(meta, rec)
+		}
+		return nil
+	}
+	return applyImport(objs, change, unmatched, opts.DryRun)
+}
+
+// mergeDict merges a record into meta, descending into the dicts present in
+// both.
+func mergeDict(meta fDict, rec fDict) {
+	for k, v := range rec {
+		if sub, isDict := v.(fDict); isDict {
+			if dst, isDict := meta[k].(fDict); isDict {
+				mergeDict(dst, sub)
+				continue
+			}
+		}
+		meta[k] = deepCopy(v)
+	}
+}
+
+// applyImport writes the changes of an import, or only reports them on a dry
+// run, together with the unmatched records.
+func applyImport(objs []*object, change func(o *object, meta fDict) error, unmatched fList, dryRun bool) ([]byte, error) {
+	if dryRun {
+		_, changes, err := prepare(objs, change)
+		if err != nil {
+			return nil, err
+		}
+		list := fList{}
+		for _, rec := range changes {
+			old, new, err := rec.values()
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, fDict{"Obj": fString(rec.Obj), "Path": fString(rec.Path), "Old": old, "New": new})
+		}
+		return marshal(fDict{"Changes": list, "Unmatched": unmatched}, !Flags.UglyJSON), nil
+	}
+	imported, err := modify(objs, change)
+	if err != nil {
+		return nil, err
+	}
+	return marshal(fDict{"Imported": imported, "Unmatched": unmatched}, !Flags.UglyJSON), nil
+}
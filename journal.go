@@ -0,0 +1,185 @@
+package feta
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+func journalPath() string {
+	return site.sysPath() + ".feta/journal"
+}
+
+func lockPath() string {
+	return site.sysPath() + ".feta/lock"
+}
+
+// writeFileAtomic replaces the file at path through a synced temporary file,
+// so readers see either the old or the new content.
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	tmp, err := ioutil.TempFile(dir, "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+func syncDir(path string) error {
+	d, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// A journalEntry holds the metadata of an object as it was before a batch of
+// writes. Data is nil for objects that had no metadata.
+type journalEntry struct {
+	Path string
+	Data *string
+}
+
+// lockSite takes the write lock of the site and rolls back any batch of writes
+// left unfinished by an interrupted process.
+func lockSite() (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(lockPath()), 0755); err != nil {
+		return nil, fmt.Errorf("Couldn't create site meta dir: %v", err)
+	}
+	f, err := os.OpenFile(lockPath(), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("Couldn't open lock file: %v", err)
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("Couldn't lock site: %v", err)
+	}
+	unlock := func() {
+		unlockFile(f)
+		f.Close()
+	}
+	if err := recoverJournal(); err != nil {
+		unlock()
+		return nil, err
+	}
+	return unlock, nil
+}
+
+// writeBatch writes the metadata of all objects and appends the records of
+// the changes to the history, or writes none of them if anything fails. The
+// previous metadata is kept in the journal of the site until the batch is
+// complete.
+func writeBatch(objs []*object, metas []fDict, records []historyRecord) error {
+	st, err := getStorage()
+	if err != nil {
+		return err
+	}
+	entries := make([]journalEntry, len(objs))
+	for i, o := range objs {
+		data, err := st.read(o)
+		if err != nil {
+			return fmt.Errorf("%v at %s", err, o.fetaPath())
+		}
+		entries[i].Path = o.fetaPath()
+		if data != nil {
+			s := string(data)
+			entries[i].Data = &s
+		}
+	}
+	js, err := json.Marshal(entries)
+	if err != nil {
+		return fmt.Errorf("Couldn't encode journal: %v", err)
+	}
+	if err := writeFileAtomic(journalPath(), js); err != nil {
+		return fmt.Errorf("Couldn't write journal: %v", err)
+	}
+	for i, o := range objs {
+		if err := o.writeMeta(metas[i]); err != nil {
+			if rbErr := rollback(entries[:i+1]); rbErr != nil {
+				return fmt.Errorf("%v at %s, and couldn't roll back: %v", err, o.fetaPath(), rbErr)
+			}
+			return fmt.Errorf("%v at %s, rolled back", err, o.fetaPath())
+		}
+	}
+	if err := appendHistory(records); err != nil {
+		if rbErr := rollback(entries); rbErr != nil {
+			return fmt.Errorf("%v, and couldn't roll back: %v", err, rbErr)
+		}
+		return fmt.Errorf("%v, rolled back", err)
+	}
+	return removeJournal()
+}
+
+func removeJournal() error {
+	if err := os.Remove(journalPath()); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("Couldn't remove journal: %v", err)
+	}
+	return nil
+}
+
+// rollback restores the metadata recorded in journal entries and removes the
+// journal.
+func rollback(entries []journalEntry) error {
+	st, err := getStorage()
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		o, err := site.lookup(e.Path)
+		if err != nil {
+			Log(fmt.Sprintf("Skipping rollback of missing object %s: %v", e.Path, err))
+			continue
+		}
+		if e.Data == nil {
+			err = st.remove(o)
+		} else {
+			err = st.write(o, []byte(*e.Data))
+		}
+		if err != nil {
+			return fmt.Errorf("%v at %s", err, e.Path)
+		}
+		o.meta = nil
+	}
+	return removeJournal()
+}
+
+func recoverJournal() error {
+	js, err := ioutil.ReadFile(journalPath())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("Couldn't read journal: %v", err)
+	}
+	var entries []journalEntry
+	if err := json.Unmarshal(js, &entries); err != nil {
+		return fmt.Errorf("Couldn't decode journal: %v", err)
+	}
+	Log(fmt.Sprintf("Rolling back interrupted batch of %d objects", len(entries)))
+	if err := rollback(entries); err != nil {
+		return fmt.Errorf("Couldn't recover interrupted batch: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,18 @@
+//go:build windows
+
+package feta
+
+import (
+	"os"
+)
+
+// Advisory locks aren't supported on Windows, so writes aren't serialized
+// there.
+
+func lockFile(f *os.File) error {
+	return nil
+}
+
+func unlockFile(f *os.File) error {
+	return nil
+}
@@ -1,6 +1,7 @@
 package feta
 
 import (
+	"fmt"
 	"sort"
 	"strconv"
 	"strings"
@@ -18,25 +19,40 @@ type fNode interface {
 }
 
 type mshState struct {
-	res    []byte
-	pretty bool
-	indent int
+	res      []byte
+	pretty   bool
+	indent   int
+	comments commentTable // comments of a sidecar, written in pretty mode
+	path     string       // path of the container being written
 }
 
 func marshal(node fNode, pretty bool) []byte {
 	res := make([]byte, 0, startSize)
-	st := &mshState{res, pretty, 0}
+	st := &mshState{res: res, pretty: pretty}
 	node.marshal(st)
 	st.res = append(st.res, '\n')
 	return st.res
 }
 
+// inline returns the compact serialization of a value without the trailing
+// newline.
+func inline(value fExpr) string {
+	res := marshal(value.(fNode), false)
+	return string(res[:len(res)-1])
+}
+
 func (value *object) marshal(st *mshState) {
 	st.res = append(st.res, "`"+value.fetaPath()+"`"...)
 }
 
+func (value fRef) marshal(st *mshState) {
+	st.res = append(st.res, "`"+string(value)+"`"...)
+}
+
 func (value fError) marshal(st *mshState) {
-	st.res = append(st.res, "error{\""+value.msg+"\"}"...)
+	st.res = append(st.res, "error{"...)
+	st.res = appendQuoted(st.res, value.msg)
+	st.res = append(st.res, '}')
 }
 
 func (value fBool) marshal(st *mshState) {
@@ -55,93 +71,327 @@ func (value fNumber) marshal(st *mshState) {
 	st.res = append(st.res, strconv.FormatFloat(float64(value), 'f', -1, 64)...)
 }
 
+func (value fDate) marshal(st *mshState) {
+	st.res = append(st.res, 'd')
+	st.res = appendQuoted(st.res, formatDate(value))
+}
+
+func (value fDuration) marshal(st *mshState) {
+	st.res = append(st.res, formatDuration(value)...)
+}
+
 func (value fString) marshal(st *mshState) {
-	st.res = append(st.res, "\""+string(value)+"\""...)
+	st.res = appendQuoted(st.res, string(value))
+}
+
+// appendQuoted appends s as a string literal, escaping the quotes,
+// backslashes and control characters the grammar doesn't allow unescaped.
+func appendQuoted(res []byte, s string) []byte {
+	res = append(res, '"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			res = append(res, `\"`...)
+		case '\\':
+			res = append(res, `\\`...)
+		case '\n':
+			res = append(res, `\n`...)
+		case '\r':
+			res = append(res, `\r`...)
+		case '\t':
+			res = append(res, `\t`...)
+		case '\b':
+			res = append(res, `\b`...)
+		case '\f':
+			res = append(res, `\f`...)
+		default:
+			if r < 0x20 {
+				res = append(res, fmt.Sprintf(`\u%04x`, r)...)
+			} else {
+				res = append(res, string(r)...)
+			}
+		}
+	}
+	return append(res, '"')
 }
 
 func (value fDict) marshal(st *mshState) {
-	var ind string
-	if st.pretty {
-		st.res = append(st.res, "{\n"...)
-		st.indent++
-	} else {
-		st.res = append(st.res, '{')
+	keys := sortedKeys(value)
+	items := make([]fExpr, len(keys))
+	for i, k := range keys {
+		items[i] = value[k]
 	}
-	ind = strings.Repeat(" ", st.indent*indentWidth)
+	st.marshalItems('{', '}', keys, items)
+}
+
+func sortedKeys(value fDict) []string {
 	keys := make([]string, 0, len(value))
 	for k := range value {
 		keys = append(keys, k)
 	}
 	sort.Sort(natural.StringSlice(keys))
-	i := 0
-	for _, k := range keys {
-		if st.pretty {
-			st.res = append(st.res, ind...)
-		}
-		st.res = append(st.res, (k + ": ")...)
-		value[k].(fNode).marshal(st)
-		if i < len(value)-1 {
-			if st.pretty {
-				st.res = append(st.res, ",\n"...)
-			} else {
+	return keys
+}
+
+func (value fList) marshal(st *mshState) {
+	st.marshalItems('[', ']', nil, value)
+}
+
+// marshalKey writes a dict key, quoted unless it's an identifier.
+func (st *mshState) marshalKey(key string) {
+	if identRex.MatchString(key) {
+		st.res = append(st.res, key...)
+	} else {
+		st.res = appendQuoted(st.res, key)
+	}
+	st.res = append(st.res, ": "...)
+}
+
+// marshalItems writes the entries of a dict, or the elements of a list when
+// keys is nil, with their comments in pretty mode.
+func (st *mshState) marshalItems(open byte, close byte, keys []string, items []fExpr) {
+	var own *comments
+	if st.pretty {
+		own = st.comments[st.path]
+	}
+	if len(items) == 0 && (own == nil || len(own.end) == 0) {
+		st.res = append(st.res, open, close)
+		return
+	}
+	st.res = append(st.res, open)
+	if !st.pretty {
+		for i, item := range items {
+			if i > 0 {
 				st.res = append(st.res, ',')
 			}
+			if keys != nil {
+				st.marshalKey(keys[i])
+			}
+			item.(fNode).marshal(st)
 		}
-		i++
+		st.res = append(st.res, close)
+		return
 	}
-	if st.pretty {
-		st.indent--
-		ind = strings.Repeat(" ", st.indent*indentWidth)
-		st.res = append(st.res, ("\n" + ind + "}")...)
-	} else {
-		st.res = append(st.res, '}')
+	st.res = append(st.res, '\n')
+	st.indent++
+	ind := strings.Repeat(" ", st.indent*indentWidth)
+	path := st.path
+	for i, item := range items {
+		name := indexName(i)
+		if keys != nil {
+			name = pathKey(keys[i])
+		}
+		st.path = childPath(path, name)
+		cs := st.comments[st.path]
+		if cs != nil {
+			st.appendLines(cs.before, ind)
+		}
+		st.res = append(st.res, ind...)
+		if keys != nil {
+			st.marshalKey(keys[i])
+		}
+		item.(fNode).marshal(st)
+		hasAfter := cs != nil && len(cs.after) > 0
+		if i < len(items)-1 || hasAfter {
+			// A trailing comment always follows a comma, so that it can't
+			// be taken for a continuation of the value.
+			st.res = append(st.res, ',')
+		}
+		if hasAfter {
+			st.res = append(st.res, (" " + strings.Join(cs.after, " "))...)
+		}
+		st.res = append(st.res, '\n')
 	}
+	st.path = path
+	if own != nil {
+		st.appendLines(own.end, ind)
+	}
+	st.indent--
+	ind = strings.Repeat(" ", st.indent*indentWidth)
+	st.res = append(st.res, ind...)
+	st.res = append(st.res, close)
 }
 
-func (value fList) marshal(st *mshState) {
-	var ind string
-	if st.pretty {
-		st.res = append(st.res, "[\n"...)
-		st.indent++
+func (node *valueRes) marshal(st *mshState) {
+	if node.raw {
+		st.res = append(st.res, '@')
+	}
+	node.expr.(fNode).marshal(st)
+	marshalChain(st, node.next)
+}
+
+func (node *attribRes) marshal(st *mshState) {
+	if node.raw {
+		st.res = append(st.res, '@')
+	}
+	if node.identifier == "" {
+		st.res = append(st.res, '~')
 	} else {
-		st.res = append(st.res, '[')
+		st.res = append(st.res, node.identifier...)
 	}
-	ind = strings.Repeat(" ", st.indent*indentWidth)
-	last := len(value) - 1
-	for i, v := range value {
-		if st.pretty {
-			st.res = append(st.res, ind...)
-		}
-		v.(fNode).marshal(st)
-		if i < last {
-			if st.pretty {
-				st.res = append(st.res, ",\n"...)
+	marshalChain(st, node.next)
+}
+
+// marshalChain writes the resolvers following the first value of a
+// resolution.
+func marshalChain(st *mshState, next resolver) {
+	for next != nil {
+		switch n := next.(type) {
+		case *attribRes:
+			if n.optional {
+				st.res = append(st.res, '?')
+			}
+			st.res = append(st.res, '.')
+			if n.identifier == "" {
+				st.res = append(st.res, '~')
 			} else {
-				st.res = append(st.res, ',')
+				st.res = append(st.res, n.identifier...)
+			}
+			next = n.next
+		case *indexRes:
+			st.res = append(st.res, '[')
+			n.expr.(fNode).marshal(st)
+			st.res = append(st.res, ']')
+			next = n.next
+		case *sliceRes:
+			st.res = append(st.res, '[')
+			if n.start != nil {
+				n.start.(fNode).marshal(st)
+			}
+			st.res = append(st.res, ':')
+			if n.stop != nil {
+				n.stop.(fNode).marshal(st)
+			}
+			if n.step != nil {
+				st.res = append(st.res, ':')
+				n.step.(fNode).marshal(st)
 			}
+			st.res = append(st.res, ']')
+			next = n.next
+		default:
+			return
 		}
 	}
-	if st.pretty {
-		st.indent--
-		ind = strings.Repeat(" ", st.indent*indentWidth)
-		st.res = append(st.res, ("\n" + ind + "]")...)
-	} else {
-		st.res = append(st.res, ']')
-	}
+}
+
+var compareOps = map[byte]string{
+	EQ:     "==",
+	NEQ:    "!=",
+	LEEQ:   "<=",
+	GREQ:   ">=",
+	LE:     "<",
+	GR:     ">",
+	IN:     " in ",
+	NIN:    " not in ",
+	MATCH:  "=~",
+	NMATCH: "!~",
+}
+
+func (node *compareNode) marshal(st *mshState) {
+	node.left.(fNode).marshal(st)
+	st.res = append(st.res, compareOps[node.op]...)
+	node.right.(fNode).marshal(st)
+}
+
+func (node *andNode) marshal(st *mshState) {
+	node.left.(fNode).marshal(st)
+	st.res = append(st.res, "&&"...)
+	node.right.(fNode).marshal(st)
+}
+
+func (node *orNode) marshal(st *mshState) {
+	node.left.(fNode).marshal(st)
+	st.res = append(st.res, "||"...)
+	node.right.(fNode).marshal(st)
+}
+
+func (node *queryNode) marshal(st *mshState) {
+	st.res = append(st.res, node.src...)
 }
 
 func (node *multNode) marshal(st *mshState) {
 	node.left.(fNode).marshal(st)
-	st.res = append(st.res, node.op)
+	st.res = append(st.res, node.op...)
 	node.right.(fNode).marshal(st)
 }
 
+func (node *powNode) marshal(st *mshState) {
+	node.base.(fNode).marshal(st)
+	st.res = append(st.res, "**"...)
+	node.exponent.(fNode).marshal(st)
+}
+
+func (node *negNode) marshal(st *mshState) {
+	st.res = append(st.res, '-')
+	node.expr.(fNode).marshal(st)
+}
+
 func (node *addNode) marshal(st *mshState) {
 	node.left.(fNode).marshal(st)
 	st.res = append(st.res, node.op)
 	node.right.(fNode).marshal(st)
 }
 
+func (node *coalesceNode) marshal(st *mshState) {
+	node.left.(fNode).marshal(st)
+	st.res = append(st.res, "??"...)
+	node.right.(fNode).marshal(st)
+}
+
+func (node *condNode) marshal(st *mshState) {
+	node.cond.(fNode).marshal(st)
+	st.res = append(st.res, '?')
+	node.then.(fNode).marshal(st)
+	st.res = append(st.res, ':')
+	node.els.(fNode).marshal(st)
+}
+
+func (node *callNode) marshal(st *mshState) {
+	st.res = append(st.res, (node.name + "(")...)
+	for i, arg := range node.args {
+		if i > 0 {
+			st.res = append(st.res, ',')
+		}
+		arg.(fNode).marshal(st)
+	}
+	st.res = append(st.res, ')')
+}
+
+func marshalLambda(st *mshState, params []string, body fExpr) {
+	if len(params) == 1 {
+		st.res = append(st.res, params[0]...)
+	} else {
+		st.res = append(st.res, ("(" + strings.Join(params, ",") + ")")...)
+	}
+	st.res = append(st.res, "->"...)
+	body.(fNode).marshal(st)
+}
+
+func (node *lambdaNode) marshal(st *mshState) {
+	marshalLambda(st, node.params, node.body)
+}
+
+func (value fLambda) marshal(st *mshState) {
+	marshalLambda(st, value.params, value.body)
+}
+
+func (node *letNode) marshal(st *mshState) {
+	st.res = append(st.res, "let "...)
+	for i, bnd := range node.bindings {
+		if i > 0 {
+			st.res = append(st.res, ',')
+		}
+		st.res = append(st.res, (bnd.name + "=")...)
+		bnd.value.(fNode).marshal(st)
+	}
+	st.res = append(st.res, " in "...)
+	node.body.(fNode).marshal(st)
+}
+
+func (node *varNode) marshal(st *mshState) {
+	st.res = append(st.res, ("$" + node.name)...)
+}
+
 func (node *compoundNode) marshal(st *mshState) {
 	st.res = append(st.res, '(')
 	node.expr.(fNode).marshal(st)
@@ -156,3 +406,11 @@ func (node *notNode) marshal(st *mshState) {
 func (node *objProc) marshal(st *mshState) {
 	st.res = append(st.res, "objProc{}"...)
 }
+
+func (node *defaultedProc) marshal(st *mshState) {
+	st.res = append(st.res, "defaultedProc{}"...)
+}
+
+func (node *historyProc) marshal(st *mshState) {
+	st.res = append(st.res, "historyProc{}"...)
+}
@@ -5,7 +5,6 @@ import (
 	"errors"
 	"fmt"
 	"io/fs"
-	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
@@ -16,12 +15,16 @@ var (
 )
 
 type object struct {
-	dirEntry  os.DirEntry
-	parent    *object
-	isProjSet bool
-	project   *object
-	children  []*object
-	meta      fDict
+	dirEntry    os.DirEntry
+	parent      *object
+	isProjSet   bool
+	project     *object
+	isSchemaSet bool
+	schema      fDict
+	children    []*object
+	meta        fDict
+	defaulted   []string
+	comments    commentTable
 }
 
 func newObject(parent *object, dirEntry os.DirEntry) (o *object) {
@@ -102,6 +105,34 @@ func (o *object) find(pathList []string) (*object, error) {
 	return nil, errors.New("Can't find it..")
 }
 
+// lookup finds the object at a feta path, which is either absolute or
+// relative to o.
+func (o *object) lookup(path string) (*object, error) {
+	if strings.HasPrefix(path, "/") {
+		o = site
+	}
+	for _, name := range strings.Split(path, "/") {
+		switch name {
+		case "", ".":
+		case "..":
+			if o.parent == nil {
+				return nil, fmt.Errorf("Path '%s' leads outside of site.", path)
+			}
+			o = o.parent
+		default:
+			if !o.dirEntry.IsDir() {
+				return nil, fmt.Errorf("Can't find '%s' in non-directory.", name)
+			}
+			ch, err := o.find([]string{name})
+			if err != nil {
+				return nil, err
+			}
+			o = ch
+		}
+	}
+	return o, nil
+}
+
 func getObject(path string) (*object, error) {
 	if path == Flags.SitePath {
 		return site, nil
@@ -126,43 +157,97 @@ func InitSite(path string) (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("Couldn't stat site path '%s': %v", absPath, err)
 	}
+	if err := closeStorage(); err != nil {
+		return "", fmt.Errorf("Couldn't close storage: %v", err)
+	}
 	site = newObject(nil, fs.FileInfoToDirEntry(fi))
+	history = nil
 	Log(fmt.Sprintf("Site set to: %s", absPath))
 	return absPath, nil
 }
 
-func (o *object) getMeta() (fDict, error) {
-	if o.meta != nil {
-		return o.meta, nil
-	}
+func (o *object) metaPath() string {
 	path := o.sysPath()
 	if o.dirEntry.IsDir() {
-		path = path + ".feta/_"
-	} else {
-		path = filepath.Dir(path) + "/.feta/" + o.dirEntry.Name() + "._"
+		return path + ".feta/_"
 	}
+	return filepath.Dir(path) + "/.feta/" + o.dirEntry.Name() + "._"
+}
 
-	js, err := ioutil.ReadFile(path)
+// readMeta parses the stored metadata of the object without caching it or
+// inserting procedurals. Objects without metadata have an empty dict.
+func (o *object) readMeta() (fDict, error) {
+	st, err := getStorage()
 	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			meta := fDict{}
-			insertProcedurals(meta)
-			return meta, nil
-		}
-		return nil, fmt.Errorf("Couldn't read meta file: %v", err)
+		return nil, err
+	}
+	data, err := st.read(o)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return fDict{}, nil
 	}
-	meta, err := Parse(path, js, Entrypoint("Expression"))
+	path := st.location(o)
+	dict, comments, err := parseSidecar(path, data)
 	if err != nil {
 		return nil, fmt.Errorf("Couldn't parse meta file '%s': %v", path, err)
 	}
-	insertProcedurals(meta.(fDict))
-	o.meta = meta.(fDict)
+	o.comments = comments
+	return dict, nil
+}
+
+// writeMeta replaces the stored metadata of the object with the given dict,
+// keeping the comments of the entries read last.
+func (o *object) writeMeta(meta fDict) error {
+	st, err := getStorage()
+	if err != nil {
+		return err
+	}
+	if err := st.write(o, marshalMeta(meta, o.comments)); err != nil {
+		return err
+	}
+	o.meta = nil
+	return nil
+}
+
+func (o *object) getMeta() (fDict, error) {
+	if o.meta != nil {
+		return o.meta, nil
+	}
+	meta, err := o.readMeta()
+	if err != nil {
+		return nil, err
+	}
+	defaults, err := o.defaults()
+	if err != nil {
+		return nil, err
+	}
+	o.defaulted = insertDefaults(meta, defaults)
+	insertProcedurals(meta)
+	o.meta = meta
 	return o.meta, nil
 }
 
+// insertDefaults adds the defaults missing from meta and returns their names.
+func insertDefaults(meta fDict, defaults fDict) []string {
+	inserted := []string{}
+	for _, k := range sortedKeys(defaults) {
+		if _, exists := meta[k]; !exists {
+			meta[k] = defaults[k]
+			inserted = append(inserted, k)
+		}
+	}
+	return inserted
+}
+
+// insertProcedurals adds the procedurals to meta, leaving attributes of the
+// same name in place.
 func insertProcedurals(meta fDict) {
 	for k, v := range procedurals {
-		meta[k] = v
+		if _, exists := meta[k]; !exists {
+			meta[k] = v
+		}
 	}
 }
 
@@ -14,6 +14,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"unicode"
 	"unicode/utf8"
 )
@@ -25,27 +26,47 @@ func toList(v interface{}) []interface{} {
 	return v.([]interface{})
 }
 
+// The "depth" state counts the nesting of expressions, while the bits of the
+// "noIn" state mark the depths where a bare 'in' terminates a let binding
+// instead of being a membership test.
+
+func stateInt(c *current, key string) int {
+	if v, ok := c.state[key].(int); ok {
+		return v
+	}
+	return 0
+}
+
+func forbidIn(c *current, forbid bool) {
+	bit := 1 << uint(stateInt(c, "depth"))
+	if forbid {
+		c.state["noIn"] = stateInt(c, "noIn") | bit
+	} else {
+		c.state["noIn"] = stateInt(c, "noIn") &^ bit
+	}
+}
+
 var g = &grammar{
 	rules: []*rule{
 		{
 			name: "QueryLine",
-			pos:  position{line: 18, col: 1, offset: 178},
+			pos:  position{line: 38, col: 1, offset: 683},
 			expr: &actionExpr{
-				pos: position{line: 18, col: 13, offset: 190},
+				pos: position{line: 38, col: 13, offset: 695},
 				run: (*parser).callonQueryLine1,
 				expr: &seqExpr{
-					pos: position{line: 18, col: 13, offset: 190},
+					pos: position{line: 38, col: 13, offset: 695},
 					exprs: []interface{}{
 						&labeledExpr{
-							pos:   position{line: 18, col: 13, offset: 190},
+							pos:   position{line: 38, col: 13, offset: 695},
 							label: "query",
 							expr: &ruleRefExpr{
-								pos:  position{line: 18, col: 19, offset: 196},
+								pos:  position{line: 38, col: 19, offset: 701},
 								name: "Query",
 							},
 						},
 						&ruleRefExpr{
-							pos:  position{line: 18, col: 25, offset: 202},
+							pos:  position{line: 38, col: 25, offset: 707},
 							name: "EOF",
 						},
 					},
@@ -54,31 +75,31 @@ var g = &grammar{
 		},
 		{
 			name: "Query",
-			pos:  position{line: 22, col: 1, offset: 230},
+			pos:  position{line: 42, col: 1, offset: 735},
 			expr: &actionExpr{
-				pos: position{line: 22, col: 9, offset: 238},
+				pos: position{line: 42, col: 9, offset: 743},
 				run: (*parser).callonQuery1,
 				expr: &seqExpr{
-					pos: position{line: 22, col: 9, offset: 238},
+					pos: position{line: 42, col: 9, offset: 743},
 					exprs: []interface{}{
 						&labeledExpr{
-							pos:   position{line: 22, col: 9, offset: 238},
+							pos:   position{line: 42, col: 9, offset: 743},
 							label: "sels_",
 							expr: &zeroOrMoreExpr{
-								pos: position{line: 22, col: 15, offset: 244},
+								pos: position{line: 42, col: 15, offset: 749},
 								expr: &ruleRefExpr{
-									pos:  position{line: 22, col: 15, offset: 244},
+									pos:  position{line: 42, col: 15, offset: 749},
 									name: "Selector",
 								},
 							},
 						},
 						&labeledExpr{
-							pos:   position{line: 22, col: 25, offset: 254},
+							pos:   position{line: 42, col: 25, offset: 759},
 							label: "tail",
 							expr: &zeroOrOneExpr{
-								pos: position{line: 22, col: 30, offset: 259},
+								pos: position{line: 42, col: 30, offset: 764},
 								expr: &ruleRefExpr{
-									pos:  position{line: 22, col: 30, offset: 259},
+									pos:  position{line: 42, col: 30, offset: 764},
 									name: "Tail",
 								},
 							},
@@ -87,116 +108,312 @@ var g = &grammar{
 				},
 			},
 		},
+		{
+			name: "Sidecar",
+			pos:  position{line: 87, col: 1, offset: 1709},
+			expr: &actionExpr{
+				pos: position{line: 87, col: 11, offset: 1719},
+				run: (*parser).callonSidecar1,
+				expr: &seqExpr{
+					pos: position{line: 87, col: 11, offset: 1719},
+					exprs: []interface{}{
+						&labeledExpr{
+							pos:   position{line: 87, col: 11, offset: 1719},
+							label: "lead",
+							expr: &ruleRefExpr{
+								pos:  position{line: 87, col: 16, offset: 1724},
+								name: "Gap",
+							},
+						},
+						&labeledExpr{
+							pos:   position{line: 87, col: 20, offset: 1728},
+							label: "expr",
+							expr: &ruleRefExpr{
+								pos:  position{line: 87, col: 25, offset: 1733},
+								name: "Expression",
+							},
+						},
+						&labeledExpr{
+							pos:   position{line: 87, col: 36, offset: 1744},
+							label: "trail",
+							expr: &ruleRefExpr{
+								pos:  position{line: 87, col: 42, offset: 1750},
+								name: "Gap",
+							},
+						},
+						&ruleRefExpr{
+							pos:  position{line: 87, col: 46, offset: 1754},
+							name: "EOF",
+						},
+					},
+				},
+			},
+		},
 		{
 			name: "Expression",
-			pos:  position{line: 67, col: 1, offset: 1115},
+			pos:  position{line: 93, col: 1, offset: 1838},
 			expr: &actionExpr{
-				pos: position{line: 67, col: 14, offset: 1128},
+				pos: position{line: 93, col: 14, offset: 1851},
 				run: (*parser).callonExpression1,
 				expr: &seqExpr{
-					pos: position{line: 67, col: 14, offset: 1128},
+					pos: position{line: 93, col: 14, offset: 1851},
 					exprs: []interface{}{
+						&stateCodeExpr{
+							pos: position{line: 93, col: 14, offset: 1851},
+							run: (*parser).callonExpression3,
+						},
 						&ruleRefExpr{
-							pos:  position{line: 67, col: 14, offset: 1128},
+							pos:  position{line: 96, col: 3, offset: 1913},
 							name: "_",
 						},
 						&labeledExpr{
-							pos:   position{line: 67, col: 16, offset: 1130},
-							label: "first",
+							pos:   position{line: 96, col: 5, offset: 1915},
+							label: "expr",
+							expr: &choiceExpr{
+								pos: position{line: 96, col: 11, offset: 1921},
+								alternatives: []interface{}{
+									&ruleRefExpr{
+										pos:  position{line: 96, col: 11, offset: 1921},
+										name: "Let",
+									},
+									&ruleRefExpr{
+										pos:  position{line: 96, col: 17, offset: 1927},
+										name: "Conditional",
+									},
+								},
+							},
+						},
+						&ruleRefExpr{
+							pos:  position{line: 96, col: 30, offset: 1940},
+							name: "_",
+						},
+						&stateCodeExpr{
+							pos: position{line: 96, col: 32, offset: 1942},
+							run: (*parser).callonExpression10,
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Let",
+			pos:  position{line: 103, col: 1, offset: 2027},
+			expr: &actionExpr{
+				pos: position{line: 103, col: 7, offset: 2033},
+				run: (*parser).callonLet1,
+				expr: &seqExpr{
+					pos: position{line: 103, col: 7, offset: 2033},
+					exprs: []interface{}{
+						&litMatcher{
+							pos:        position{line: 103, col: 7, offset: 2033},
+							val:        "let",
+							ignoreCase: false,
+							want:       "\"let\"",
+						},
+						&notExpr{
+							pos: position{line: 103, col: 13, offset: 2039},
 							expr: &ruleRefExpr{
-								pos:  position{line: 67, col: 22, offset: 1136},
-								name: "Level_A",
+								pos:  position{line: 103, col: 14, offset: 2040},
+								name: "IdentChar",
 							},
 						},
 						&ruleRefExpr{
-							pos:  position{line: 67, col: 30, offset: 1144},
+							pos:  position{line: 103, col: 24, offset: 2050},
 							name: "_",
 						},
 						&labeledExpr{
-							pos:   position{line: 67, col: 32, offset: 1146},
+							pos:   position{line: 103, col: 26, offset: 2052},
+							label: "first",
+							expr: &ruleRefExpr{
+								pos:  position{line: 103, col: 32, offset: 2058},
+								name: "Binding",
+							},
+						},
+						&labeledExpr{
+							pos:   position{line: 103, col: 40, offset: 2066},
 							label: "rest_",
 							expr: &zeroOrMoreExpr{
-								pos: position{line: 67, col: 38, offset: 1152},
+								pos: position{line: 103, col: 46, offset: 2072},
 								expr: &seqExpr{
-									pos: position{line: 67, col: 39, offset: 1153},
+									pos: position{line: 103, col: 47, offset: 2073},
 									exprs: []interface{}{
 										&ruleRefExpr{
-											pos:  position{line: 67, col: 39, offset: 1153},
-											name: "Or",
+											pos:  position{line: 103, col: 47, offset: 2073},
+											name: "_",
+										},
+										&litMatcher{
+											pos:        position{line: 103, col: 49, offset: 2075},
+											val:        ",",
+											ignoreCase: false,
+											want:       "\",\"",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 67, col: 42, offset: 1156},
+											pos:  position{line: 103, col: 53, offset: 2079},
 											name: "_",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 67, col: 44, offset: 1158},
-											name: "Level_A",
+											pos:  position{line: 103, col: 55, offset: 2081},
+											name: "Binding",
 										},
 									},
 								},
 							},
 						},
 						&ruleRefExpr{
-							pos:  position{line: 67, col: 54, offset: 1168},
+							pos:  position{line: 103, col: 65, offset: 2091},
 							name: "_",
 						},
+						&litMatcher{
+							pos:        position{line: 103, col: 67, offset: 2093},
+							val:        "in",
+							ignoreCase: false,
+							want:       "\"in\"",
+						},
+						&notExpr{
+							pos: position{line: 103, col: 72, offset: 2098},
+							expr: &ruleRefExpr{
+								pos:  position{line: 103, col: 73, offset: 2099},
+								name: "IdentChar",
+							},
+						},
+						&labeledExpr{
+							pos:   position{line: 103, col: 83, offset: 2109},
+							label: "body",
+							expr: &ruleRefExpr{
+								pos:  position{line: 103, col: 88, offset: 2114},
+								name: "Expression",
+							},
+						},
 					},
 				},
 			},
 		},
 		{
-			name: "Or",
-			pos:  position{line: 80, col: 1, offset: 1389},
+			name: "Binding",
+			pos:  position{line: 113, col: 1, offset: 2364},
 			expr: &actionExpr{
-				pos: position{line: 80, col: 6, offset: 1394},
-				run: (*parser).callonOr1,
-				expr: &litMatcher{
-					pos:        position{line: 80, col: 6, offset: 1394},
-					val:        "||",
-					ignoreCase: false,
+				pos: position{line: 113, col: 11, offset: 2374},
+				run: (*parser).callonBinding1,
+				expr: &seqExpr{
+					pos: position{line: 113, col: 11, offset: 2374},
+					exprs: []interface{}{
+						&zeroOrOneExpr{
+							pos: position{line: 113, col: 11, offset: 2374},
+							expr: &litMatcher{
+								pos:        position{line: 113, col: 11, offset: 2374},
+								val:        "$",
+								ignoreCase: false,
+								want:       "\"$\"",
+							},
+						},
+						&labeledExpr{
+							pos:   position{line: 113, col: 16, offset: 2379},
+							label: "name",
+							expr: &ruleRefExpr{
+								pos:  position{line: 113, col: 21, offset: 2384},
+								name: "Identifier",
+							},
+						},
+						&ruleRefExpr{
+							pos:  position{line: 113, col: 32, offset: 2395},
+							name: "_",
+						},
+						&litMatcher{
+							pos:        position{line: 113, col: 34, offset: 2397},
+							val:        "=",
+							ignoreCase: false,
+							want:       "\"=\"",
+						},
+						&notExpr{
+							pos: position{line: 113, col: 38, offset: 2401},
+							expr: &litMatcher{
+								pos:        position{line: 113, col: 39, offset: 2402},
+								val:        "=",
+								ignoreCase: false,
+								want:       "\"=\"",
+							},
+						},
+						&ruleRefExpr{
+							pos:  position{line: 113, col: 43, offset: 2406},
+							name: "_",
+						},
+						&stateCodeExpr{
+							pos: position{line: 113, col: 45, offset: 2408},
+							run: (*parser).callonBinding12,
+						},
+						&labeledExpr{
+							pos:   position{line: 116, col: 3, offset: 2444},
+							label: "value",
+							expr: &ruleRefExpr{
+								pos:  position{line: 116, col: 9, offset: 2450},
+								name: "Conditional",
+							},
+						},
+						&stateCodeExpr{
+							pos: position{line: 116, col: 21, offset: 2462},
+							run: (*parser).callonBinding15,
+						},
+					},
 				},
 			},
 		},
 		{
-			name: "Level_A",
-			pos:  position{line: 84, col: 1, offset: 1427},
+			name: "Conditional",
+			pos:  position{line: 123, col: 1, offset: 2583},
 			expr: &actionExpr{
-				pos: position{line: 84, col: 11, offset: 1437},
-				run: (*parser).callonLevel_A1,
+				pos: position{line: 123, col: 15, offset: 2597},
+				run: (*parser).callonConditional1,
 				expr: &seqExpr{
-					pos: position{line: 84, col: 11, offset: 1437},
+					pos: position{line: 123, col: 15, offset: 2597},
 					exprs: []interface{}{
 						&labeledExpr{
-							pos:   position{line: 84, col: 11, offset: 1437},
-							label: "first",
+							pos:   position{line: 123, col: 15, offset: 2597},
+							label: "cond",
 							expr: &ruleRefExpr{
-								pos:  position{line: 84, col: 17, offset: 1443},
-								name: "Level_B",
+								pos:  position{line: 123, col: 20, offset: 2602},
+								name: "Coalescence",
 							},
 						},
-						&ruleRefExpr{
-							pos:  position{line: 84, col: 25, offset: 1451},
-							name: "_",
-						},
 						&labeledExpr{
-							pos:   position{line: 84, col: 27, offset: 1453},
-							label: "rest_",
-							expr: &zeroOrMoreExpr{
-								pos: position{line: 84, col: 33, offset: 1459},
+							pos:   position{line: 123, col: 32, offset: 2614},
+							label: "branches_",
+							expr: &zeroOrOneExpr{
+								pos: position{line: 123, col: 42, offset: 2624},
 								expr: &seqExpr{
-									pos: position{line: 84, col: 34, offset: 1460},
+									pos: position{line: 123, col: 43, offset: 2625},
 									exprs: []interface{}{
 										&ruleRefExpr{
-											pos:  position{line: 84, col: 34, offset: 1460},
-											name: "And",
+											pos:  position{line: 123, col: 43, offset: 2625},
+											name: "_",
+										},
+										&litMatcher{
+											pos:        position{line: 123, col: 45, offset: 2627},
+											val:        "?",
+											ignoreCase: false,
+											want:       "\"?\"",
+										},
+										&notExpr{
+											pos: position{line: 123, col: 49, offset: 2631},
+											expr: &litMatcher{
+												pos:        position{line: 123, col: 50, offset: 2632},
+												val:        "?",
+												ignoreCase: false,
+												want:       "\"?\"",
+											},
 										},
 										&ruleRefExpr{
-											pos:  position{line: 84, col: 38, offset: 1464},
-											name: "_",
+											pos:  position{line: 123, col: 54, offset: 2636},
+											name: "Expression",
+										},
+										&litMatcher{
+											pos:        position{line: 123, col: 65, offset: 2647},
+											val:        ":",
+											ignoreCase: false,
+											want:       "\":\"",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 84, col: 40, offset: 1466},
-											name: "Level_B",
+											pos:  position{line: 123, col: 69, offset: 2651},
+											name: "Expression",
 										},
 									},
 								},
@@ -207,58 +424,45 @@ var g = &grammar{
 			},
 		},
 		{
-			name: "And",
-			pos:  position{line: 97, col: 1, offset: 1696},
-			expr: &actionExpr{
-				pos: position{line: 97, col: 7, offset: 1702},
-				run: (*parser).callonAnd1,
-				expr: &litMatcher{
-					pos:        position{line: 97, col: 7, offset: 1702},
-					val:        "&&",
-					ignoreCase: false,
-				},
-			},
-		},
-		{
-			name: "Level_B",
-			pos:  position{line: 101, col: 1, offset: 1736},
+			name: "Coalescence",
+			pos:  position{line: 135, col: 1, offset: 2852},
 			expr: &actionExpr{
-				pos: position{line: 101, col: 11, offset: 1746},
-				run: (*parser).callonLevel_B1,
+				pos: position{line: 135, col: 15, offset: 2866},
+				run: (*parser).callonCoalescence1,
 				expr: &seqExpr{
-					pos: position{line: 101, col: 11, offset: 1746},
+					pos: position{line: 135, col: 15, offset: 2866},
 					exprs: []interface{}{
 						&labeledExpr{
-							pos:   position{line: 101, col: 11, offset: 1746},
+							pos:   position{line: 135, col: 15, offset: 2866},
 							label: "first",
 							expr: &ruleRefExpr{
-								pos:  position{line: 101, col: 17, offset: 1752},
-								name: "Level_C",
+								pos:  position{line: 135, col: 21, offset: 2872},
+								name: "Disjunction",
 							},
 						},
-						&ruleRefExpr{
-							pos:  position{line: 101, col: 25, offset: 1760},
-							name: "_",
-						},
 						&labeledExpr{
-							pos:   position{line: 101, col: 27, offset: 1762},
+							pos:   position{line: 135, col: 33, offset: 2884},
 							label: "rest_",
 							expr: &zeroOrMoreExpr{
-								pos: position{line: 101, col: 33, offset: 1768},
+								pos: position{line: 135, col: 39, offset: 2890},
 								expr: &seqExpr{
-									pos: position{line: 101, col: 34, offset: 1769},
+									pos: position{line: 135, col: 40, offset: 2891},
 									exprs: []interface{}{
 										&ruleRefExpr{
-											pos:  position{line: 101, col: 34, offset: 1769},
-											name: "Comparison",
+											pos:  position{line: 135, col: 40, offset: 2891},
+											name: "_",
+										},
+										&ruleRefExpr{
+											pos:  position{line: 135, col: 42, offset: 2893},
+											name: "Coalesce",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 101, col: 45, offset: 1780},
+											pos:  position{line: 135, col: 51, offset: 2902},
 											name: "_",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 101, col: 47, offset: 1782},
-											name: "Level_C",
+											pos:  position{line: 135, col: 53, offset: 2904},
+											name: "Disjunction",
 										},
 									},
 								},
@@ -269,88 +473,59 @@ var g = &grammar{
 			},
 		},
 		{
-			name: "Comparison",
-			pos:  position{line: 114, col: 1, offset: 2016},
+			name: "Coalesce",
+			pos:  position{line: 148, col: 1, offset: 3142},
 			expr: &actionExpr{
-				pos: position{line: 114, col: 14, offset: 2029},
-				run: (*parser).callonComparison1,
-				expr: &choiceExpr{
-					pos: position{line: 114, col: 15, offset: 2030},
-					alternatives: []interface{}{
-						&litMatcher{
-							pos:        position{line: 114, col: 15, offset: 2030},
-							val:        "==",
-							ignoreCase: false,
-						},
-						&litMatcher{
-							pos:        position{line: 114, col: 22, offset: 2037},
-							val:        "!=",
-							ignoreCase: false,
-						},
-						&litMatcher{
-							pos:        position{line: 114, col: 29, offset: 2044},
-							val:        "<=",
-							ignoreCase: false,
-						},
-						&litMatcher{
-							pos:        position{line: 114, col: 36, offset: 2051},
-							val:        ">=",
-							ignoreCase: false,
-						},
-						&litMatcher{
-							pos:        position{line: 114, col: 43, offset: 2058},
-							val:        "<",
-							ignoreCase: false,
-						},
-						&litMatcher{
-							pos:        position{line: 114, col: 49, offset: 2064},
-							val:        ">",
-							ignoreCase: false,
-						},
-					},
+				pos: position{line: 148, col: 12, offset: 3153},
+				run: (*parser).callonCoalesce1,
+				expr: &litMatcher{
+					pos:        position{line: 148, col: 12, offset: 3153},
+					val:        "??",
+					ignoreCase: false,
+					want:       "\"??\"",
 				},
 			},
 		},
 		{
-			name: "Level_C",
-			pos:  position{line: 130, col: 1, offset: 2375},
+			name: "Disjunction",
+			pos:  position{line: 152, col: 1, offset: 3192},
 			expr: &actionExpr{
-				pos: position{line: 130, col: 11, offset: 2385},
-				run: (*parser).callonLevel_C1,
+				pos: position{line: 152, col: 15, offset: 3206},
+				run: (*parser).callonDisjunction1,
 				expr: &seqExpr{
-					pos: position{line: 130, col: 11, offset: 2385},
+					pos: position{line: 152, col: 15, offset: 3206},
 					exprs: []interface{}{
 						&labeledExpr{
-							pos:   position{line: 130, col: 11, offset: 2385},
+							pos:   position{line: 152, col: 15, offset: 3206},
 							label: "first",
 							expr: &ruleRefExpr{
-								pos:  position{line: 130, col: 17, offset: 2391},
-								name: "Level_D",
+								pos:  position{line: 152, col: 21, offset: 3212},
+								name: "Level_A",
 							},
 						},
 						&ruleRefExpr{
-							pos:  position{line: 130, col: 25, offset: 2399},
+							pos:  position{line: 152, col: 29, offset: 3220},
 							name: "_",
 						},
 						&labeledExpr{
-							pos:   position{line: 130, col: 27, offset: 2401},
+							pos:   position{line: 152, col: 31, offset: 3222},
 							label: "rest_",
 							expr: &zeroOrMoreExpr{
-								pos: position{line: 130, col: 33, offset: 2407},
+								pos: position{line: 152, col: 37, offset: 3228},
 								expr: &seqExpr{
-									pos: position{line: 130, col: 34, offset: 2408},
+									pos: position{line: 152, col: 38, offset: 3229},
 									exprs: []interface{}{
 										&ruleRefExpr{
-											pos:  position{line: 130, col: 34, offset: 2408},
-											name: "Additive",
+											pos:  position{line: 152, col: 38, offset: 3229},
+											name: "Or",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 130, col: 43, offset: 2417},
+											pos:  position{line: 152, col: 41, offset: 3232},
 											name: "_",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 130, col: 45, offset: 2419},
-											name: "Level_D",
+											pos:  position{line: 152, col: 43, offset: 3234},
+											name: "Level_A",
 										},
 									},
 								},
@@ -361,68 +536,59 @@ var g = &grammar{
 			},
 		},
 		{
-			name: "Additive",
-			pos:  position{line: 143, col: 1, offset: 2649},
+			name: "Or",
+			pos:  position{line: 165, col: 1, offset: 3463},
 			expr: &actionExpr{
-				pos: position{line: 143, col: 12, offset: 2660},
-				run: (*parser).callonAdditive1,
-				expr: &choiceExpr{
-					pos: position{line: 143, col: 13, offset: 2661},
-					alternatives: []interface{}{
-						&litMatcher{
-							pos:        position{line: 143, col: 13, offset: 2661},
-							val:        "+",
-							ignoreCase: false,
-						},
-						&litMatcher{
-							pos:        position{line: 143, col: 19, offset: 2667},
-							val:        "-",
-							ignoreCase: false,
-						},
-					},
+				pos: position{line: 165, col: 6, offset: 3468},
+				run: (*parser).callonOr1,
+				expr: &litMatcher{
+					pos:        position{line: 165, col: 6, offset: 3468},
+					val:        "||",
+					ignoreCase: false,
+					want:       "\"||\"",
 				},
 			},
 		},
 		{
-			name: "Level_D",
-			pos:  position{line: 147, col: 1, offset: 2714},
+			name: "Level_A",
+			pos:  position{line: 169, col: 1, offset: 3501},
 			expr: &actionExpr{
-				pos: position{line: 147, col: 11, offset: 2724},
-				run: (*parser).callonLevel_D1,
+				pos: position{line: 169, col: 11, offset: 3511},
+				run: (*parser).callonLevel_A1,
 				expr: &seqExpr{
-					pos: position{line: 147, col: 11, offset: 2724},
+					pos: position{line: 169, col: 11, offset: 3511},
 					exprs: []interface{}{
 						&labeledExpr{
-							pos:   position{line: 147, col: 11, offset: 2724},
+							pos:   position{line: 169, col: 11, offset: 3511},
 							label: "first",
 							expr: &ruleRefExpr{
-								pos:  position{line: 147, col: 17, offset: 2730},
-								name: "Level_E",
+								pos:  position{line: 169, col: 17, offset: 3517},
+								name: "Level_B",
 							},
 						},
 						&ruleRefExpr{
-							pos:  position{line: 147, col: 25, offset: 2738},
+							pos:  position{line: 169, col: 25, offset: 3525},
 							name: "_",
 						},
 						&labeledExpr{
-							pos:   position{line: 147, col: 27, offset: 2740},
+							pos:   position{line: 169, col: 27, offset: 3527},
 							label: "rest_",
 							expr: &zeroOrMoreExpr{
-								pos: position{line: 147, col: 33, offset: 2746},
+								pos: position{line: 169, col: 33, offset: 3533},
 								expr: &seqExpr{
-									pos: position{line: 147, col: 34, offset: 2747},
+									pos: position{line: 169, col: 34, offset: 3534},
 									exprs: []interface{}{
 										&ruleRefExpr{
-											pos:  position{line: 147, col: 34, offset: 2747},
-											name: "Multiplicative",
+											pos:  position{line: 169, col: 34, offset: 3534},
+											name: "And",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 147, col: 49, offset: 2762},
+											pos:  position{line: 169, col: 38, offset: 3538},
 											name: "_",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 147, col: 51, offset: 2764},
-											name: "Level_E",
+											pos:  position{line: 169, col: 40, offset: 3540},
+											name: "Level_B",
 										},
 									},
 								},
@@ -433,103 +599,1179 @@ var g = &grammar{
 			},
 		},
 		{
-			name: "Multiplicative",
-			pos:  position{line: 160, col: 1, offset: 2995},
+			name: "And",
+			pos:  position{line: 182, col: 1, offset: 3770},
 			expr: &actionExpr{
-				pos: position{line: 160, col: 18, offset: 3012},
-				run: (*parser).callonMultiplicative1,
-				expr: &choiceExpr{
-					pos: position{line: 160, col: 19, offset: 3013},
-					alternatives: []interface{}{
-						&litMatcher{
-							pos:        position{line: 160, col: 19, offset: 3013},
-							val:        "*",
-							ignoreCase: false,
-						},
-						&litMatcher{
-							pos:        position{line: 160, col: 25, offset: 3019},
-							val:        "/",
-							ignoreCase: false,
-						},
-					},
+				pos: position{line: 182, col: 7, offset: 3776},
+				run: (*parser).callonAnd1,
+				expr: &litMatcher{
+					pos:        position{line: 182, col: 7, offset: 3776},
+					val:        "&&",
+					ignoreCase: false,
+					want:       "\"&&\"",
 				},
 			},
 		},
 		{
-			name: "Level_E",
-			pos:  position{line: 164, col: 1, offset: 3067},
+			name: "Level_B",
+			pos:  position{line: 186, col: 1, offset: 3810},
 			expr: &actionExpr{
-				pos: position{line: 164, col: 11, offset: 3077},
-				run: (*parser).callonLevel_E1,
+				pos: position{line: 186, col: 11, offset: 3820},
+				run: (*parser).callonLevel_B1,
 				expr: &seqExpr{
-					pos: position{line: 164, col: 11, offset: 3077},
+					pos: position{line: 186, col: 11, offset: 3820},
 					exprs: []interface{}{
 						&labeledExpr{
-							pos:   position{line: 164, col: 11, offset: 3077},
-							label: "op",
-							expr: &zeroOrOneExpr{
-								pos: position{line: 164, col: 14, offset: 3080},
-								expr: &litMatcher{
-									pos:        position{line: 164, col: 14, offset: 3080},
-									val:        "!",
-									ignoreCase: false,
-								},
+							pos:   position{line: 186, col: 11, offset: 3820},
+							label: "first",
+							expr: &ruleRefExpr{
+								pos:  position{line: 186, col: 17, offset: 3826},
+								name: "Level_C",
 							},
 						},
 						&ruleRefExpr{
-							pos:  position{line: 164, col: 19, offset: 3085},
+							pos:  position{line: 186, col: 25, offset: 3834},
 							name: "_",
 						},
 						&labeledExpr{
-							pos:   position{line: 164, col: 21, offset: 3087},
-							label: "operand",
+							pos:   position{line: 186, col: 27, offset: 3836},
+							label: "rest_",
+							expr: &zeroOrMoreExpr{
+								pos: position{line: 186, col: 33, offset: 3842},
+								expr: &seqExpr{
+									pos: position{line: 186, col: 34, offset: 3843},
+									exprs: []interface{}{
+										&ruleRefExpr{
+											pos:  position{line: 186, col: 34, offset: 3843},
+											name: "Comparison",
+										},
+										&ruleRefExpr{
+											pos:  position{line: 186, col: 45, offset: 3854},
+											name: "_",
+										},
+										&ruleRefExpr{
+											pos:  position{line: 186, col: 47, offset: 3856},
+											name: "Level_C",
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Comparison",
+			pos:  position{line: 199, col: 1, offset: 4090},
+			expr: &choiceExpr{
+				pos: position{line: 199, col: 14, offset: 4103},
+				alternatives: []interface{}{
+					&actionExpr{
+						pos: position{line: 199, col: 14, offset: 4103},
+						run: (*parser).callonComparison2,
+						expr: &choiceExpr{
+							pos: position{line: 199, col: 15, offset: 4104},
+							alternatives: []interface{}{
+								&litMatcher{
+									pos:        position{line: 199, col: 15, offset: 4104},
+									val:        "=~",
+									ignoreCase: false,
+									want:       "\"=~\"",
+								},
+								&litMatcher{
+									pos:        position{line: 199, col: 22, offset: 4111},
+									val:        "!~",
+									ignoreCase: false,
+									want:       "\"!~\"",
+								},
+								&litMatcher{
+									pos:        position{line: 199, col: 29, offset: 4118},
+									val:        "==",
+									ignoreCase: false,
+									want:       "\"==\"",
+								},
+								&litMatcher{
+									pos:        position{line: 199, col: 36, offset: 4125},
+									val:        "!=",
+									ignoreCase: false,
+									want:       "\"!=\"",
+								},
+								&litMatcher{
+									pos:        position{line: 199, col: 43, offset: 4132},
+									val:        "<=",
+									ignoreCase: false,
+									want:       "\"<=\"",
+								},
+								&litMatcher{
+									pos:        position{line: 199, col: 50, offset: 4139},
+									val:        ">=",
+									ignoreCase: false,
+									want:       "\">=\"",
+								},
+								&litMatcher{
+									pos:        position{line: 199, col: 57, offset: 4146},
+									val:        "<",
+									ignoreCase: false,
+									want:       "\"<\"",
+								},
+								&litMatcher{
+									pos:        position{line: 199, col: 63, offset: 4152},
+									val:        ">",
+									ignoreCase: false,
+									want:       "\">\"",
+								},
+							},
+						},
+					},
+					&actionExpr{
+						pos: position{line: 217, col: 5, offset: 4565},
+						run: (*parser).callonComparison12,
+						expr: &seqExpr{
+							pos: position{line: 217, col: 5, offset: 4565},
+							exprs: []interface{}{
+								&litMatcher{
+									pos:        position{line: 217, col: 5, offset: 4565},
+									val:        "in",
+									ignoreCase: false,
+									want:       "\"in\"",
+								},
+								&notExpr{
+									pos: position{line: 217, col: 10, offset: 4570},
+									expr: &ruleRefExpr{
+										pos:  position{line: 217, col: 11, offset: 4571},
+										name: "IdentChar",
+									},
+								},
+								&andCodeExpr{
+									pos: position{line: 217, col: 21, offset: 4581},
+									run: (*parser).callonComparison17,
+								},
+							},
+						},
+					},
+					&actionExpr{
+						pos: position{line: 221, col: 5, offset: 4696},
+						run: (*parser).callonComparison18,
+						expr: &seqExpr{
+							pos: position{line: 221, col: 5, offset: 4696},
+							exprs: []interface{}{
+								&litMatcher{
+									pos:        position{line: 221, col: 5, offset: 4696},
+									val:        "not",
+									ignoreCase: false,
+									want:       "\"not\"",
+								},
+								&oneOrMoreExpr{
+									pos: position{line: 221, col: 11, offset: 4702},
+									expr: &charClassMatcher{
+										pos:        position{line: 221, col: 11, offset: 4702},
+										val:        "[ \\t\\r\\n]",
+										chars:      []rune{' ', '\t', '\r', '\n'},
+										ignoreCase: false,
+										inverted:   false,
+									},
+								},
+								&litMatcher{
+									pos:        position{line: 221, col: 22, offset: 4713},
+									val:        "in",
+									ignoreCase: false,
+									want:       "\"in\"",
+								},
+								&notExpr{
+									pos: position{line: 221, col: 27, offset: 4718},
+									expr: &ruleRefExpr{
+										pos:  position{line: 221, col: 28, offset: 4719},
+										name: "IdentChar",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Level_C",
+			pos:  position{line: 225, col: 1, offset: 4769},
+			expr: &actionExpr{
+				pos: position{line: 225, col: 11, offset: 4779},
+				run: (*parser).callonLevel_C1,
+				expr: &seqExpr{
+					pos: position{line: 225, col: 11, offset: 4779},
+					exprs: []interface{}{
+						&labeledExpr{
+							pos:   position{line: 225, col: 11, offset: 4779},
+							label: "first",
+							expr: &ruleRefExpr{
+								pos:  position{line: 225, col: 17, offset: 4785},
+								name: "Level_D",
+							},
+						},
+						&ruleRefExpr{
+							pos:  position{line: 225, col: 25, offset: 4793},
+							name: "_",
+						},
+						&labeledExpr{
+							pos:   position{line: 225, col: 27, offset: 4795},
+							label: "rest_",
+							expr: &zeroOrMoreExpr{
+								pos: position{line: 225, col: 33, offset: 4801},
+								expr: &seqExpr{
+									pos: position{line: 225, col: 34, offset: 4802},
+									exprs: []interface{}{
+										&ruleRefExpr{
+											pos:  position{line: 225, col: 34, offset: 4802},
+											name: "Additive",
+										},
+										&ruleRefExpr{
+											pos:  position{line: 225, col: 43, offset: 4811},
+											name: "_",
+										},
+										&ruleRefExpr{
+											pos:  position{line: 225, col: 45, offset: 4813},
+											name: "Level_D",
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Additive",
+			pos:  position{line: 238, col: 1, offset: 5043},
+			expr: &actionExpr{
+				pos: position{line: 238, col: 12, offset: 5054},
+				run: (*parser).callonAdditive1,
+				expr: &choiceExpr{
+					pos: position{line: 238, col: 13, offset: 5055},
+					alternatives: []interface{}{
+						&litMatcher{
+							pos:        position{line: 238, col: 13, offset: 5055},
+							val:        "+",
+							ignoreCase: false,
+							want:       "\"+\"",
+						},
+						&litMatcher{
+							pos:        position{line: 238, col: 19, offset: 5061},
+							val:        "-",
+							ignoreCase: false,
+							want:       "\"-\"",
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Level_D",
+			pos:  position{line: 242, col: 1, offset: 5108},
+			expr: &actionExpr{
+				pos: position{line: 242, col: 11, offset: 5118},
+				run: (*parser).callonLevel_D1,
+				expr: &seqExpr{
+					pos: position{line: 242, col: 11, offset: 5118},
+					exprs: []interface{}{
+						&labeledExpr{
+							pos:   position{line: 242, col: 11, offset: 5118},
+							label: "first",
+							expr: &ruleRefExpr{
+								pos:  position{line: 242, col: 17, offset: 5124},
+								name: "Level_E",
+							},
+						},
+						&ruleRefExpr{
+							pos:  position{line: 242, col: 25, offset: 5132},
+							name: "_",
+						},
+						&labeledExpr{
+							pos:   position{line: 242, col: 27, offset: 5134},
+							label: "rest_",
+							expr: &zeroOrMoreExpr{
+								pos: position{line: 242, col: 33, offset: 5140},
+								expr: &seqExpr{
+									pos: position{line: 242, col: 34, offset: 5141},
+									exprs: []interface{}{
+										&ruleRefExpr{
+											pos:  position{line: 242, col: 34, offset: 5141},
+											name: "Multiplicative",
+										},
+										&ruleRefExpr{
+											pos:  position{line: 242, col: 49, offset: 5156},
+											name: "_",
+										},
+										&ruleRefExpr{
+											pos:  position{line: 242, col: 51, offset: 5158},
+											name: "Level_E",
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Multiplicative",
+			pos:  position{line: 255, col: 1, offset: 5389},
+			expr: &actionExpr{
+				pos: position{line: 255, col: 18, offset: 5406},
+				run: (*parser).callonMultiplicative1,
+				expr: &choiceExpr{
+					pos: position{line: 255, col: 19, offset: 5407},
+					alternatives: []interface{}{
+						&litMatcher{
+							pos:        position{line: 255, col: 19, offset: 5407},
+							val:        "//",
+							ignoreCase: false,
+							want:       "\"//\"",
+						},
+						&litMatcher{
+							pos:        position{line: 255, col: 26, offset: 5414},
+							val:        "*",
+							ignoreCase: false,
+							want:       "\"*\"",
+						},
+						&litMatcher{
+							pos:        position{line: 255, col: 32, offset: 5420},
+							val:        "/",
+							ignoreCase: false,
+							want:       "\"/\"",
+						},
+						&litMatcher{
+							pos:        position{line: 255, col: 38, offset: 5426},
+							val:        "%",
+							ignoreCase: false,
+							want:       "\"%\"",
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Level_E",
+			pos:  position{line: 259, col: 1, offset: 5479},
+			expr: &choiceExpr{
+				pos: position{line: 259, col: 11, offset: 5489},
+				alternatives: []interface{}{
+					&actionExpr{
+						pos: position{line: 259, col: 11, offset: 5489},
+						run: (*parser).callonLevel_E2,
+						expr: &seqExpr{
+							pos: position{line: 259, col: 11, offset: 5489},
+							exprs: []interface{}{
+								&labeledExpr{
+									pos:   position{line: 259, col: 11, offset: 5489},
+									label: "op",
+									expr: &choiceExpr{
+										pos: position{line: 259, col: 15, offset: 5493},
+										alternatives: []interface{}{
+											&litMatcher{
+												pos:        position{line: 259, col: 15, offset: 5493},
+												val:        "!",
+												ignoreCase: false,
+												want:       "\"!\"",
+											},
+											&litMatcher{
+												pos:        position{line: 259, col: 21, offset: 5499},
+												val:        "-",
+												ignoreCase: false,
+												want:       "\"-\"",
+											},
+										},
+									},
+								},
+								&ruleRefExpr{
+									pos:  position{line: 259, col: 26, offset: 5504},
+									name: "_",
+								},
+								&labeledExpr{
+									pos:   position{line: 259, col: 28, offset: 5506},
+									label: "operand",
+									expr: &ruleRefExpr{
+										pos:  position{line: 259, col: 36, offset: 5514},
+										name: "Level_E",
+									},
+								},
+							},
+						},
+					},
+					&ruleRefExpr{
+						pos:  position{line: 267, col: 5, offset: 5700},
+						name: "Level_F",
+					},
+				},
+			},
+		},
+		{
+			name: "Level_F",
+			pos:  position{line: 269, col: 1, offset: 5709},
+			expr: &actionExpr{
+				pos: position{line: 269, col: 11, offset: 5719},
+				run: (*parser).callonLevel_F1,
+				expr: &seqExpr{
+					pos: position{line: 269, col: 11, offset: 5719},
+					exprs: []interface{}{
+						&labeledExpr{
+							pos:   position{line: 269, col: 11, offset: 5719},
+							label: "base",
 							expr: &ruleRefExpr{
-								pos:  position{line: 164, col: 29, offset: 3095},
+								pos:  position{line: 269, col: 16, offset: 5724},
 								name: "Resolution",
 							},
 						},
+						&labeledExpr{
+							pos:   position{line: 269, col: 27, offset: 5735},
+							label: "exponent_",
+							expr: &zeroOrOneExpr{
+								pos: position{line: 269, col: 37, offset: 5745},
+								expr: &seqExpr{
+									pos: position{line: 269, col: 38, offset: 5746},
+									exprs: []interface{}{
+										&ruleRefExpr{
+											pos:  position{line: 269, col: 38, offset: 5746},
+											name: "_",
+										},
+										&litMatcher{
+											pos:        position{line: 269, col: 40, offset: 5748},
+											val:        "**",
+											ignoreCase: false,
+											want:       "\"**\"",
+										},
+										&ruleRefExpr{
+											pos:  position{line: 269, col: 45, offset: 5753},
+											name: "_",
+										},
+										&ruleRefExpr{
+											pos:  position{line: 269, col: 47, offset: 5755},
+											name: "Level_E",
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Resolution",
+			pos:  position{line: 276, col: 1, offset: 5897},
+			expr: &actionExpr{
+				pos: position{line: 276, col: 14, offset: 5910},
+				run: (*parser).callonResolution1,
+				expr: &seqExpr{
+					pos: position{line: 276, col: 14, offset: 5910},
+					exprs: []interface{}{
+						&labeledExpr{
+							pos:   position{line: 276, col: 14, offset: 5910},
+							label: "isRaw",
+							expr: &zeroOrOneExpr{
+								pos: position{line: 276, col: 20, offset: 5916},
+								expr: &litMatcher{
+									pos:        position{line: 276, col: 20, offset: 5916},
+									val:        "@",
+									ignoreCase: false,
+									want:       "\"@\"",
+								},
+							},
+						},
+						&labeledExpr{
+							pos:   position{line: 276, col: 25, offset: 5921},
+							label: "first",
+							expr: &ruleRefExpr{
+								pos:  position{line: 276, col: 31, offset: 5927},
+								name: "Value",
+							},
+						},
+						&labeledExpr{
+							pos:   position{line: 276, col: 37, offset: 5933},
+							label: "rest_",
+							expr: &zeroOrMoreExpr{
+								pos: position{line: 276, col: 43, offset: 5939},
+								expr: &ruleRefExpr{
+									pos:  position{line: 276, col: 43, offset: 5939},
+									name: "Resolver",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Resolver",
+			pos:  position{line: 302, col: 1, offset: 6460},
+			expr: &choiceExpr{
+				pos: position{line: 302, col: 12, offset: 6471},
+				alternatives: []interface{}{
+					&ruleRefExpr{
+						pos:  position{line: 302, col: 12, offset: 6471},
+						name: "Attribute",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 302, col: 24, offset: 6483},
+						name: "Slice",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 302, col: 32, offset: 6491},
+						name: "Index",
+					},
+				},
+			},
+		},
+		{
+			name: "Slice",
+			pos:  position{line: 304, col: 1, offset: 6498},
+			expr: &actionExpr{
+				pos: position{line: 304, col: 9, offset: 6506},
+				run: (*parser).callonSlice1,
+				expr: &seqExpr{
+					pos: position{line: 304, col: 9, offset: 6506},
+					exprs: []interface{}{
+						&litMatcher{
+							pos:        position{line: 304, col: 9, offset: 6506},
+							val:        "[",
+							ignoreCase: false,
+							want:       "\"[\"",
+						},
+						&ruleRefExpr{
+							pos:  position{line: 304, col: 13, offset: 6510},
+							name: "_",
+						},
+						&labeledExpr{
+							pos:   position{line: 304, col: 15, offset: 6512},
+							label: "start",
+							expr: &zeroOrOneExpr{
+								pos: position{line: 304, col: 21, offset: 6518},
+								expr: &ruleRefExpr{
+									pos:  position{line: 304, col: 21, offset: 6518},
+									name: "Expression",
+								},
+							},
+						},
+						&litMatcher{
+							pos:        position{line: 304, col: 33, offset: 6530},
+							val:        ":",
+							ignoreCase: false,
+							want:       "\":\"",
+						},
+						&ruleRefExpr{
+							pos:  position{line: 304, col: 37, offset: 6534},
+							name: "_",
+						},
+						&labeledExpr{
+							pos:   position{line: 304, col: 39, offset: 6536},
+							label: "stop",
+							expr: &zeroOrOneExpr{
+								pos: position{line: 304, col: 44, offset: 6541},
+								expr: &ruleRefExpr{
+									pos:  position{line: 304, col: 44, offset: 6541},
+									name: "Expression",
+								},
+							},
+						},
+						&labeledExpr{
+							pos:   position{line: 304, col: 56, offset: 6553},
+							label: "step_",
+							expr: &zeroOrOneExpr{
+								pos: position{line: 304, col: 62, offset: 6559},
+								expr: &seqExpr{
+									pos: position{line: 304, col: 63, offset: 6560},
+									exprs: []interface{}{
+										&litMatcher{
+											pos:        position{line: 304, col: 63, offset: 6560},
+											val:        ":",
+											ignoreCase: false,
+											want:       "\":\"",
+										},
+										&ruleRefExpr{
+											pos:  position{line: 304, col: 67, offset: 6564},
+											name: "_",
+										},
+										&zeroOrOneExpr{
+											pos: position{line: 304, col: 69, offset: 6566},
+											expr: &ruleRefExpr{
+												pos:  position{line: 304, col: 69, offset: 6566},
+												name: "Expression",
+											},
+										},
+									},
+								},
+							},
+						},
+						&ruleRefExpr{
+							pos:  position{line: 304, col: 83, offset: 6580},
+							name: "_",
+						},
+						&litMatcher{
+							pos:        position{line: 304, col: 85, offset: 6582},
+							val:        "]",
+							ignoreCase: false,
+							want:       "\"]\"",
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Index",
+			pos:  position{line: 320, col: 1, offset: 6828},
+			expr: &actionExpr{
+				pos: position{line: 320, col: 9, offset: 6836},
+				run: (*parser).callonIndex1,
+				expr: &seqExpr{
+					pos: position{line: 320, col: 9, offset: 6836},
+					exprs: []interface{}{
+						&litMatcher{
+							pos:        position{line: 320, col: 9, offset: 6836},
+							val:        "[",
+							ignoreCase: false,
+							want:       "\"[\"",
+						},
+						&ruleRefExpr{
+							pos:  position{line: 320, col: 13, offset: 6840},
+							name: "_",
+						},
+						&labeledExpr{
+							pos:   position{line: 320, col: 15, offset: 6842},
+							label: "expr",
+							expr: &ruleRefExpr{
+								pos:  position{line: 320, col: 20, offset: 6847},
+								name: "Expression",
+							},
+						},
+						&ruleRefExpr{
+							pos:  position{line: 320, col: 31, offset: 6858},
+							name: "_",
+						},
+						&litMatcher{
+							pos:        position{line: 320, col: 33, offset: 6860},
+							val:        "]",
+							ignoreCase: false,
+							want:       "\"]\"",
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Attribute",
+			pos:  position{line: 324, col: 1, offset: 6912},
+			expr: &choiceExpr{
+				pos: position{line: 324, col: 13, offset: 6924},
+				alternatives: []interface{}{
+					&actionExpr{
+						pos: position{line: 324, col: 13, offset: 6924},
+						run: (*parser).callonAttribute2,
+						expr: &seqExpr{
+							pos: position{line: 324, col: 13, offset: 6924},
+							exprs: []interface{}{
+								&litMatcher{
+									pos:        position{line: 324, col: 13, offset: 6924},
+									val:        ".",
+									ignoreCase: false,
+									want:       "\".\"",
+								},
+								&labeledExpr{
+									pos:   position{line: 324, col: 17, offset: 6928},
+									label: "identifier",
+									expr: &ruleRefExpr{
+										pos:  position{line: 324, col: 28, offset: 6939},
+										name: "Identifier",
+									},
+								},
+							},
+						},
+					},
+					&actionExpr{
+						pos: position{line: 326, col: 5, offset: 6980},
+						run: (*parser).callonAttribute7,
+						expr: &seqExpr{
+							pos: position{line: 326, col: 5, offset: 6980},
+							exprs: []interface{}{
+								&litMatcher{
+									pos:        position{line: 326, col: 5, offset: 6980},
+									val:        "?.",
+									ignoreCase: false,
+									want:       "\"?.\"",
+								},
+								&labeledExpr{
+									pos:   position{line: 326, col: 10, offset: 6985},
+									label: "identifier",
+									expr: &ruleRefExpr{
+										pos:  position{line: 326, col: 21, offset: 6996},
+										name: "Identifier",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Value",
+			pos:  position{line: 331, col: 1, offset: 7077},
+			expr: &choiceExpr{
+				pos: position{line: 331, col: 10, offset: 7086},
+				alternatives: []interface{}{
+					&ruleRefExpr{
+						pos:  position{line: 331, col: 10, offset: 7086},
+						name: "Bool",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 331, col: 17, offset: 7093},
+						name: "None",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 331, col: 24, offset: 7100},
+						name: "Date",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 331, col: 31, offset: 7107},
+						name: "Duration",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 331, col: 42, offset: 7118},
+						name: "Number",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 331, col: 51, offset: 7127},
+						name: "String",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 331, col: 60, offset: 7136},
+						name: "Reference",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 331, col: 72, offset: 7148},
+						name: "Call",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 331, col: 79, offset: 7155},
+						name: "Variable",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 331, col: 90, offset: 7166},
+						name: "Identifier",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 331, col: 103, offset: 7179},
+						name: "List",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 331, col: 110, offset: 7186},
+						name: "Dict",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 331, col: 117, offset: 7193},
+						name: "Subquery",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 331, col: 128, offset: 7204},
+						name: "Compound",
+					},
+				},
+			},
+		},
+		{
+			name: "Subquery",
+			pos:  position{line: 333, col: 1, offset: 7214},
+			expr: &actionExpr{
+				pos: position{line: 333, col: 12, offset: 7225},
+				run: (*parser).callonSubquery1,
+				expr: &seqExpr{
+					pos: position{line: 333, col: 12, offset: 7225},
+					exprs: []interface{}{
+						&litMatcher{
+							pos:        position{line: 333, col: 12, offset: 7225},
+							val:        "(|",
+							ignoreCase: false,
+							want:       "\"(|\"",
+						},
+						&ruleRefExpr{
+							pos:  position{line: 333, col: 17, offset: 7230},
+							name: "_",
+						},
+						&labeledExpr{
+							pos:   position{line: 333, col: 19, offset: 7232},
+							label: "query",
+							expr: &ruleRefExpr{
+								pos:  position{line: 333, col: 25, offset: 7238},
+								name: "Query",
+							},
+						},
+						&ruleRefExpr{
+							pos:  position{line: 333, col: 31, offset: 7244},
+							name: "_",
+						},
+						&litMatcher{
+							pos:        position{line: 333, col: 33, offset: 7246},
+							val:        ")",
+							ignoreCase: false,
+							want:       "\")\"",
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Compound",
+			pos:  position{line: 339, col: 1, offset: 7332},
+			expr: &actionExpr{
+				pos: position{line: 339, col: 12, offset: 7343},
+				run: (*parser).callonCompound1,
+				expr: &seqExpr{
+					pos: position{line: 339, col: 12, offset: 7343},
+					exprs: []interface{}{
+						&litMatcher{
+							pos:        position{line: 339, col: 12, offset: 7343},
+							val:        "(",
+							ignoreCase: false,
+							want:       "\"(\"",
+						},
+						&ruleRefExpr{
+							pos:  position{line: 339, col: 16, offset: 7347},
+							name: "_",
+						},
+						&labeledExpr{
+							pos:   position{line: 339, col: 18, offset: 7349},
+							label: "expr",
+							expr: &ruleRefExpr{
+								pos:  position{line: 339, col: 23, offset: 7354},
+								name: "Expression",
+							},
+						},
+						&ruleRefExpr{
+							pos:  position{line: 339, col: 34, offset: 7365},
+							name: "_",
+						},
+						&litMatcher{
+							pos:        position{line: 339, col: 36, offset: 7367},
+							val:        ")",
+							ignoreCase: false,
+							want:       "\")\"",
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "List",
+			pos:  position{line: 343, col: 1, offset: 7417},
+			expr: &choiceExpr{
+				pos: position{line: 343, col: 8, offset: 7424},
+				alternatives: []interface{}{
+					&actionExpr{
+						pos: position{line: 343, col: 8, offset: 7424},
+						run: (*parser).callonList2,
+						expr: &seqExpr{
+							pos: position{line: 343, col: 8, offset: 7424},
+							exprs: []interface{}{
+								&litMatcher{
+									pos:        position{line: 343, col: 8, offset: 7424},
+									val:        "[",
+									ignoreCase: false,
+									want:       "\"[\"",
+								},
+								&labeledExpr{
+									pos:   position{line: 343, col: 12, offset: 7428},
+									label: "lead",
+									expr: &ruleRefExpr{
+										pos:  position{line: 343, col: 17, offset: 7433},
+										name: "Gap",
+									},
+								},
+								&litMatcher{
+									pos:        position{line: 343, col: 21, offset: 7437},
+									val:        "]",
+									ignoreCase: false,
+									want:       "\"]\"",
+								},
+							},
+						},
+					},
+					&actionExpr{
+						pos: position{line: 345, col: 5, offset: 7477},
+						run: (*parser).callonList8,
+						expr: &seqExpr{
+							pos: position{line: 345, col: 5, offset: 7477},
+							exprs: []interface{}{
+								&litMatcher{
+									pos:        position{line: 345, col: 5, offset: 7477},
+									val:        "[",
+									ignoreCase: false,
+									want:       "\"[\"",
+								},
+								&labeledExpr{
+									pos:   position{line: 345, col: 9, offset: 7481},
+									label: "lead",
+									expr: &ruleRefExpr{
+										pos:  position{line: 345, col: 14, offset: 7486},
+										name: "Gap",
+									},
+								},
+								&labeledExpr{
+									pos:   position{line: 345, col: 18, offset: 7490},
+									label: "first",
+									expr: &ruleRefExpr{
+										pos:  position{line: 345, col: 24, offset: 7496},
+										name: "Expression",
+									},
+								},
+								&labeledExpr{
+									pos:   position{line: 345, col: 35, offset: 7507},
+									label: "gap",
+									expr: &ruleRefExpr{
+										pos:  position{line: 345, col: 39, offset: 7511},
+										name: "Gap",
+									},
+								},
+								&labeledExpr{
+									pos:   position{line: 345, col: 43, offset: 7515},
+									label: "rest",
+									expr: &zeroOrMoreExpr{
+										pos: position{line: 345, col: 48, offset: 7520},
+										expr: &seqExpr{
+											pos: position{line: 345, col: 49, offset: 7521},
+											exprs: []interface{}{
+												&litMatcher{
+													pos:        position{line: 345, col: 49, offset: 7521},
+													val:        ",",
+													ignoreCase: false,
+													want:       "\",\"",
+												},
+												&ruleRefExpr{
+													pos:  position{line: 345, col: 53, offset: 7525},
+													name: "Gap",
+												},
+												&ruleRefExpr{
+													pos:  position{line: 345, col: 57, offset: 7529},
+													name: "Expression",
+												},
+												&ruleRefExpr{
+													pos:  position{line: 345, col: 68, offset: 7540},
+													name: "Gap",
+												},
+											},
+										},
+									},
+								},
+								&labeledExpr{
+									pos:   position{line: 345, col: 74, offset: 7546},
+									label: "tail",
+									expr: &zeroOrOneExpr{
+										pos: position{line: 345, col: 79, offset: 7551},
+										expr: &seqExpr{
+											pos: position{line: 345, col: 80, offset: 7552},
+											exprs: []interface{}{
+												&litMatcher{
+													pos:        position{line: 345, col: 80, offset: 7552},
+													val:        ",",
+													ignoreCase: false,
+													want:       "\",\"",
+												},
+												&ruleRefExpr{
+													pos:  position{line: 345, col: 84, offset: 7556},
+													name: "Gap",
+												},
+											},
+										},
+									},
+								},
+								&litMatcher{
+									pos:        position{line: 345, col: 90, offset: 7562},
+									val:        "]",
+									ignoreCase: false,
+									want:       "\"]\"",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Dict",
+			pos:  position{line: 352, col: 1, offset: 7773},
+			expr: &choiceExpr{
+				pos: position{line: 352, col: 8, offset: 7780},
+				alternatives: []interface{}{
+					&actionExpr{
+						pos: position{line: 352, col: 8, offset: 7780},
+						run: (*parser).callonDict2,
+						expr: &seqExpr{
+							pos: position{line: 352, col: 8, offset: 7780},
+							exprs: []interface{}{
+								&litMatcher{
+									pos:        position{line: 352, col: 8, offset: 7780},
+									val:        "{",
+									ignoreCase: false,
+									want:       "\"{\"",
+								},
+								&labeledExpr{
+									pos:   position{line: 352, col: 12, offset: 7784},
+									label: "lead",
+									expr: &ruleRefExpr{
+										pos:  position{line: 352, col: 17, offset: 7789},
+										name: "Gap",
+									},
+								},
+								&litMatcher{
+									pos:        position{line: 352, col: 21, offset: 7793},
+									val:        "}",
+									ignoreCase: false,
+									want:       "\"}\"",
+								},
+							},
+						},
+					},
+					&actionExpr{
+						pos: position{line: 354, col: 5, offset: 7833},
+						run: (*parser).callonDict8,
+						expr: &seqExpr{
+							pos: position{line: 354, col: 5, offset: 7833},
+							exprs: []interface{}{
+								&litMatcher{
+									pos:        position{line: 354, col: 5, offset: 7833},
+									val:        "{",
+									ignoreCase: false,
+									want:       "\"{\"",
+								},
+								&labeledExpr{
+									pos:   position{line: 354, col: 9, offset: 7837},
+									label: "lead",
+									expr: &ruleRefExpr{
+										pos:  position{line: 354, col: 14, offset: 7842},
+										name: "Gap",
+									},
+								},
+								&labeledExpr{
+									pos:   position{line: 354, col: 18, offset: 7846},
+									label: "first",
+									expr: &ruleRefExpr{
+										pos:  position{line: 354, col: 24, offset: 7852},
+										name: "DictEntry",
+									},
+								},
+								&labeledExpr{
+									pos:   position{line: 354, col: 34, offset: 7862},
+									label: "gap",
+									expr: &ruleRefExpr{
+										pos:  position{line: 354, col: 38, offset: 7866},
+										name: "Gap",
+									},
+								},
+								&labeledExpr{
+									pos:   position{line: 354, col: 42, offset: 7870},
+									label: "rest",
+									expr: &zeroOrMoreExpr{
+										pos: position{line: 354, col: 47, offset: 7875},
+										expr: &seqExpr{
+											pos: position{line: 354, col: 48, offset: 7876},
+											exprs: []interface{}{
+												&litMatcher{
+													pos:        position{line: 354, col: 48, offset: 7876},
+													val:        ",",
+													ignoreCase: false,
+													want:       "\",\"",
+												},
+												&ruleRefExpr{
+													pos:  position{line: 354, col: 52, offset: 7880},
+													name: "Gap",
+												},
+												&ruleRefExpr{
+													pos:  position{line: 354, col: 56, offset: 7884},
+													name: "DictEntry",
+												},
+												&ruleRefExpr{
+													pos:  position{line: 354, col: 66, offset: 7894},
+													name: "Gap",
+												},
+											},
+										},
+									},
+								},
+								&labeledExpr{
+									pos:   position{line: 354, col: 72, offset: 7900},
+									label: "tail",
+									expr: &zeroOrOneExpr{
+										pos: position{line: 354, col: 77, offset: 7905},
+										expr: &seqExpr{
+											pos: position{line: 354, col: 78, offset: 7906},
+											exprs: []interface{}{
+												&litMatcher{
+													pos:        position{line: 354, col: 78, offset: 7906},
+													val:        ",",
+													ignoreCase: false,
+													want:       "\",\"",
+												},
+												&ruleRefExpr{
+													pos:  position{line: 354, col: 82, offset: 7910},
+													name: "Gap",
+												},
+											},
+										},
+									},
+								},
+								&litMatcher{
+									pos:        position{line: 354, col: 88, offset: 7916},
+									val:        "}",
+									ignoreCase: false,
+									want:       "\"}\"",
+								},
+							},
+						},
 					},
 				},
 			},
 		},
 		{
-			name: "Resolution",
-			pos:  position{line: 171, col: 1, offset: 3191},
+			name: "DictEntry",
+			pos:  position{line: 358, col: 1, offset: 7979},
 			expr: &actionExpr{
-				pos: position{line: 171, col: 14, offset: 3204},
-				run: (*parser).callonResolution1,
+				pos: position{line: 358, col: 13, offset: 7991},
+				run: (*parser).callonDictEntry1,
 				expr: &seqExpr{
-					pos: position{line: 171, col: 14, offset: 3204},
+					pos: position{line: 358, col: 13, offset: 7991},
 					exprs: []interface{}{
 						&labeledExpr{
-							pos:   position{line: 171, col: 14, offset: 3204},
-							label: "isRaw",
-							expr: &zeroOrOneExpr{
-								pos: position{line: 171, col: 20, offset: 3210},
-								expr: &litMatcher{
-									pos:        position{line: 171, col: 20, offset: 3210},
-									val:        "@",
-									ignoreCase: false,
+							pos:   position{line: 358, col: 13, offset: 7991},
+							label: "key_",
+							expr: &choiceExpr{
+								pos: position{line: 358, col: 19, offset: 7997},
+								alternatives: []interface{}{
+									&ruleRefExpr{
+										pos:  position{line: 358, col: 19, offset: 7997},
+										name: "Identifier",
+									},
+									&ruleRefExpr{
+										pos:  position{line: 358, col: 32, offset: 8010},
+										name: "String",
+									},
 								},
 							},
 						},
-						&labeledExpr{
-							pos:   position{line: 171, col: 25, offset: 3215},
-							label: "first",
-							expr: &ruleRefExpr{
-								pos:  position{line: 171, col: 31, offset: 3221},
-								name: "Value",
-							},
+						&litMatcher{
+							pos:        position{line: 358, col: 40, offset: 8018},
+							val:        ":",
+							ignoreCase: false,
+							want:       "\":\"",
+						},
+						&ruleRefExpr{
+							pos:  position{line: 358, col: 44, offset: 8022},
+							name: "_",
 						},
 						&labeledExpr{
-							pos:   position{line: 171, col: 37, offset: 3227},
-							label: "rest_",
-							expr: &zeroOrMoreExpr{
-								pos: position{line: 171, col: 43, offset: 3233},
-								expr: &ruleRefExpr{
-									pos:  position{line: 171, col: 43, offset: 3233},
-									name: "Resolver",
-								},
+							pos:   position{line: 358, col: 46, offset: 8024},
+							label: "value",
+							expr: &ruleRefExpr{
+								pos:  position{line: 358, col: 52, offset: 8030},
+								name: "Expression",
 							},
 						},
 					},
@@ -537,81 +1779,105 @@ var g = &grammar{
 			},
 		},
 		{
-			name: "Resolver",
-			pos:  position{line: 197, col: 1, offset: 3754},
-			expr: &choiceExpr{
-				pos: position{line: 197, col: 12, offset: 3765},
-				alternatives: []interface{}{
-					&ruleRefExpr{
-						pos:  position{line: 197, col: 12, offset: 3765},
-						name: "Attribute",
-					},
-					&ruleRefExpr{
-						pos:  position{line: 197, col: 24, offset: 3777},
-						name: "Index",
-					},
-				},
-			},
-		},
-		{
-			name: "Index",
-			pos:  position{line: 199, col: 1, offset: 3784},
+			name: "Call",
+			pos:  position{line: 369, col: 1, offset: 8243},
 			expr: &actionExpr{
-				pos: position{line: 199, col: 9, offset: 3792},
-				run: (*parser).callonIndex1,
+				pos: position{line: 369, col: 8, offset: 8250},
+				run: (*parser).callonCall1,
 				expr: &seqExpr{
-					pos: position{line: 199, col: 9, offset: 3792},
+					pos: position{line: 369, col: 8, offset: 8250},
 					exprs: []interface{}{
+						&labeledExpr{
+							pos:   position{line: 369, col: 8, offset: 8250},
+							label: "name",
+							expr: &ruleRefExpr{
+								pos:  position{line: 369, col: 13, offset: 8255},
+								name: "FunctionName",
+							},
+						},
 						&litMatcher{
-							pos:        position{line: 199, col: 9, offset: 3792},
-							val:        "[",
+							pos:        position{line: 369, col: 26, offset: 8268},
+							val:        "(",
 							ignoreCase: false,
+							want:       "\"(\"",
 						},
 						&ruleRefExpr{
-							pos:  position{line: 199, col: 13, offset: 3796},
+							pos:  position{line: 369, col: 30, offset: 8272},
 							name: "_",
 						},
 						&labeledExpr{
-							pos:   position{line: 199, col: 15, offset: 3798},
-							label: "expr",
-							expr: &ruleRefExpr{
-								pos:  position{line: 199, col: 20, offset: 3803},
-								name: "Expression",
+							pos:   position{line: 369, col: 32, offset: 8274},
+							label: "args_",
+							expr: &zeroOrOneExpr{
+								pos: position{line: 369, col: 38, offset: 8280},
+								expr: &ruleRefExpr{
+									pos:  position{line: 369, col: 38, offset: 8280},
+									name: "Arguments",
+								},
 							},
 						},
-						&ruleRefExpr{
-							pos:  position{line: 199, col: 31, offset: 3814},
-							name: "_",
-						},
 						&litMatcher{
-							pos:        position{line: 199, col: 33, offset: 3816},
-							val:        "]",
+							pos:        position{line: 369, col: 49, offset: 8291},
+							val:        ")",
 							ignoreCase: false,
+							want:       "\")\"",
 						},
 					},
 				},
 			},
 		},
 		{
-			name: "Attribute",
-			pos:  position{line: 203, col: 1, offset: 3868},
+			name: "FunctionName",
+			pos:  position{line: 377, col: 1, offset: 8421},
+			expr: &actionExpr{
+				pos: position{line: 377, col: 16, offset: 8436},
+				run: (*parser).callonFunctionName1,
+				expr: &oneOrMoreExpr{
+					pos: position{line: 377, col: 16, offset: 8436},
+					expr: &ruleRefExpr{
+						pos:  position{line: 377, col: 16, offset: 8436},
+						name: "IdentChar",
+					},
+				},
+			},
+		},
+		{
+			name: "Arguments",
+			pos:  position{line: 381, col: 1, offset: 8480},
 			expr: &actionExpr{
-				pos: position{line: 203, col: 13, offset: 3880},
-				run: (*parser).callonAttribute1,
+				pos: position{line: 381, col: 13, offset: 8492},
+				run: (*parser).callonArguments1,
 				expr: &seqExpr{
-					pos: position{line: 203, col: 13, offset: 3880},
+					pos: position{line: 381, col: 13, offset: 8492},
 					exprs: []interface{}{
-						&litMatcher{
-							pos:        position{line: 203, col: 13, offset: 3880},
-							val:        ".",
-							ignoreCase: false,
-						},
 						&labeledExpr{
-							pos:   position{line: 203, col: 17, offset: 3884},
-							label: "identifier",
+							pos:   position{line: 381, col: 13, offset: 8492},
+							label: "first",
 							expr: &ruleRefExpr{
-								pos:  position{line: 203, col: 28, offset: 3895},
-								name: "Identifier",
+								pos:  position{line: 381, col: 19, offset: 8498},
+								name: "Argument",
+							},
+						},
+						&labeledExpr{
+							pos:   position{line: 381, col: 28, offset: 8507},
+							label: "rest_",
+							expr: &zeroOrMoreExpr{
+								pos: position{line: 381, col: 34, offset: 8513},
+								expr: &seqExpr{
+									pos: position{line: 381, col: 35, offset: 8514},
+									exprs: []interface{}{
+										&litMatcher{
+											pos:        position{line: 381, col: 35, offset: 8514},
+											val:        ",",
+											ignoreCase: false,
+											want:       "\",\"",
+										},
+										&ruleRefExpr{
+											pos:  position{line: 381, col: 39, offset: 8518},
+											name: "Argument",
+										},
+									},
+								},
 							},
 						},
 					},
@@ -619,366 +1885,281 @@ var g = &grammar{
 			},
 		},
 		{
-			name: "Value",
-			pos:  position{line: 207, col: 1, offset: 3935},
+			name: "Argument",
+			pos:  position{line: 391, col: 1, offset: 8706},
 			expr: &choiceExpr{
-				pos: position{line: 207, col: 10, offset: 3944},
+				pos: position{line: 391, col: 12, offset: 8717},
 				alternatives: []interface{}{
 					&ruleRefExpr{
-						pos:  position{line: 207, col: 10, offset: 3944},
-						name: "Bool",
-					},
-					&ruleRefExpr{
-						pos:  position{line: 207, col: 17, offset: 3951},
-						name: "None",
-					},
-					&ruleRefExpr{
-						pos:  position{line: 207, col: 24, offset: 3958},
-						name: "Number",
-					},
-					&ruleRefExpr{
-						pos:  position{line: 207, col: 33, offset: 3967},
-						name: "String",
-					},
-					&ruleRefExpr{
-						pos:  position{line: 207, col: 42, offset: 3976},
-						name: "Identifier",
-					},
-					&ruleRefExpr{
-						pos:  position{line: 207, col: 55, offset: 3989},
-						name: "List",
-					},
-					&ruleRefExpr{
-						pos:  position{line: 207, col: 62, offset: 3996},
-						name: "Dict",
-					},
-					&ruleRefExpr{
-						pos:  position{line: 207, col: 69, offset: 4003},
-						name: "Subquery",
+						pos:  position{line: 391, col: 12, offset: 8717},
+						name: "Lambda",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 207, col: 80, offset: 4014},
-						name: "Compound",
+						pos:  position{line: 391, col: 21, offset: 8726},
+						name: "Expression",
 					},
 				},
 			},
 		},
 		{
-			name: "Subquery",
-			pos:  position{line: 209, col: 1, offset: 4024},
+			name: "Lambda",
+			pos:  position{line: 393, col: 1, offset: 8738},
 			expr: &actionExpr{
-				pos: position{line: 209, col: 12, offset: 4035},
-				run: (*parser).callonSubquery1,
+				pos: position{line: 393, col: 10, offset: 8747},
+				run: (*parser).callonLambda1,
 				expr: &seqExpr{
-					pos: position{line: 209, col: 12, offset: 4035},
+					pos: position{line: 393, col: 10, offset: 8747},
 					exprs: []interface{}{
-						&litMatcher{
-							pos:        position{line: 209, col: 12, offset: 4035},
-							val:        "(|",
-							ignoreCase: false,
-						},
 						&ruleRefExpr{
-							pos:  position{line: 209, col: 17, offset: 4040},
+							pos:  position{line: 393, col: 10, offset: 8747},
 							name: "_",
 						},
 						&labeledExpr{
-							pos:   position{line: 209, col: 19, offset: 4042},
-							label: "query",
+							pos:   position{line: 393, col: 12, offset: 8749},
+							label: "params",
 							expr: &ruleRefExpr{
-								pos:  position{line: 209, col: 25, offset: 4048},
-								name: "Query",
+								pos:  position{line: 393, col: 19, offset: 8756},
+								name: "LambdaParams",
 							},
 						},
 						&ruleRefExpr{
-							pos:  position{line: 209, col: 31, offset: 4054},
+							pos:  position{line: 393, col: 32, offset: 8769},
 							name: "_",
 						},
 						&litMatcher{
-							pos:        position{line: 209, col: 33, offset: 4056},
-							val:        ")",
-							ignoreCase: false,
-						},
-					},
-				},
-			},
-		},
-		{
-			name: "Compound",
-			pos:  position{line: 214, col: 1, offset: 4101},
-			expr: &actionExpr{
-				pos: position{line: 214, col: 12, offset: 4112},
-				run: (*parser).callonCompound1,
-				expr: &seqExpr{
-					pos: position{line: 214, col: 12, offset: 4112},
-					exprs: []interface{}{
-						&litMatcher{
-							pos:        position{line: 214, col: 12, offset: 4112},
-							val:        "(",
+							pos:        position{line: 393, col: 34, offset: 8771},
+							val:        "->",
 							ignoreCase: false,
-						},
-						&ruleRefExpr{
-							pos:  position{line: 214, col: 16, offset: 4116},
-							name: "_",
+							want:       "\"->\"",
 						},
 						&labeledExpr{
-							pos:   position{line: 214, col: 18, offset: 4118},
-							label: "expr",
+							pos:   position{line: 393, col: 39, offset: 8776},
+							label: "body",
 							expr: &ruleRefExpr{
-								pos:  position{line: 214, col: 23, offset: 4123},
+								pos:  position{line: 393, col: 44, offset: 8781},
 								name: "Expression",
 							},
 						},
-						&ruleRefExpr{
-							pos:  position{line: 214, col: 34, offset: 4134},
-							name: "_",
-						},
-						&litMatcher{
-							pos:        position{line: 214, col: 36, offset: 4136},
-							val:        ")",
-							ignoreCase: false,
-						},
 					},
 				},
 			},
 		},
 		{
-			name: "List",
-			pos:  position{line: 218, col: 1, offset: 4186},
-			expr: &actionExpr{
-				pos: position{line: 218, col: 8, offset: 4193},
-				run: (*parser).callonList1,
-				expr: &seqExpr{
-					pos: position{line: 218, col: 8, offset: 4193},
-					exprs: []interface{}{
-						&litMatcher{
-							pos:        position{line: 218, col: 8, offset: 4193},
-							val:        "[",
-							ignoreCase: false,
-						},
-						&ruleRefExpr{
-							pos:  position{line: 218, col: 12, offset: 4197},
-							name: "_",
-						},
-						&labeledExpr{
-							pos:   position{line: 218, col: 14, offset: 4199},
-							label: "first",
+			name: "LambdaParams",
+			pos:  position{line: 397, col: 1, offset: 8869},
+			expr: &choiceExpr{
+				pos: position{line: 397, col: 16, offset: 8884},
+				alternatives: []interface{}{
+					&actionExpr{
+						pos: position{line: 397, col: 16, offset: 8884},
+						run: (*parser).callonLambdaParams2,
+						expr: &labeledExpr{
+							pos:   position{line: 397, col: 16, offset: 8884},
+							label: "param",
 							expr: &ruleRefExpr{
-								pos:  position{line: 218, col: 20, offset: 4205},
-								name: "Expression",
+								pos:  position{line: 397, col: 22, offset: 8890},
+								name: "Identifier",
 							},
 						},
-						&ruleRefExpr{
-							pos:  position{line: 218, col: 31, offset: 4216},
-							name: "_",
-						},
-						&labeledExpr{
-							pos:   position{line: 218, col: 33, offset: 4218},
-							label: "rest_",
-							expr: &zeroOrMoreExpr{
-								pos: position{line: 218, col: 39, offset: 4224},
-								expr: &ruleRefExpr{
-									pos:  position{line: 218, col: 39, offset: 4224},
-									name: "ListElements",
+					},
+					&actionExpr{
+						pos: position{line: 399, col: 5, offset: 8960},
+						run: (*parser).callonLambdaParams5,
+						expr: &seqExpr{
+							pos: position{line: 399, col: 5, offset: 8960},
+							exprs: []interface{}{
+								&litMatcher{
+									pos:        position{line: 399, col: 5, offset: 8960},
+									val:        "(",
+									ignoreCase: false,
+									want:       "\"(\"",
+								},
+								&ruleRefExpr{
+									pos:  position{line: 399, col: 9, offset: 8964},
+									name: "_",
+								},
+								&labeledExpr{
+									pos:   position{line: 399, col: 11, offset: 8966},
+									label: "first",
+									expr: &ruleRefExpr{
+										pos:  position{line: 399, col: 17, offset: 8972},
+										name: "Identifier",
+									},
+								},
+								&labeledExpr{
+									pos:   position{line: 399, col: 28, offset: 8983},
+									label: "rest_",
+									expr: &zeroOrMoreExpr{
+										pos: position{line: 399, col: 34, offset: 8989},
+										expr: &seqExpr{
+											pos: position{line: 399, col: 35, offset: 8990},
+											exprs: []interface{}{
+												&ruleRefExpr{
+													pos:  position{line: 399, col: 35, offset: 8990},
+													name: "_",
+												},
+												&litMatcher{
+													pos:        position{line: 399, col: 37, offset: 8992},
+													val:        ",",
+													ignoreCase: false,
+													want:       "\",\"",
+												},
+												&ruleRefExpr{
+													pos:  position{line: 399, col: 41, offset: 8996},
+													name: "_",
+												},
+												&ruleRefExpr{
+													pos:  position{line: 399, col: 43, offset: 8998},
+													name: "Identifier",
+												},
+											},
+										},
+									},
+								},
+								&ruleRefExpr{
+									pos:  position{line: 399, col: 56, offset: 9011},
+									name: "_",
+								},
+								&litMatcher{
+									pos:        position{line: 399, col: 58, offset: 9013},
+									val:        ")",
+									ignoreCase: false,
+									want:       "\")\"",
 								},
 							},
 						},
-						&litMatcher{
-							pos:        position{line: 218, col: 53, offset: 4238},
-							val:        "]",
-							ignoreCase: false,
-						},
 					},
 				},
 			},
 		},
 		{
-			name: "ListElements",
-			pos:  position{line: 228, col: 1, offset: 4413},
+			name: "Reference",
+			pos:  position{line: 409, col: 1, offset: 9239},
 			expr: &actionExpr{
-				pos: position{line: 228, col: 16, offset: 4428},
-				run: (*parser).callonListElements1,
+				pos: position{line: 409, col: 13, offset: 9251},
+				run: (*parser).callonReference1,
 				expr: &seqExpr{
-					pos: position{line: 228, col: 16, offset: 4428},
+					pos: position{line: 409, col: 13, offset: 9251},
 					exprs: []interface{}{
 						&litMatcher{
-							pos:        position{line: 228, col: 16, offset: 4428},
-							val:        ",",
+							pos:        position{line: 409, col: 13, offset: 9251},
+							val:        "`",
 							ignoreCase: false,
+							want:       "\"`\"",
 						},
-						&ruleRefExpr{
-							pos:  position{line: 228, col: 20, offset: 4432},
-							name: "_",
-						},
-						&labeledExpr{
-							pos:   position{line: 228, col: 22, offset: 4434},
-							label: "expr",
-							expr: &ruleRefExpr{
-								pos:  position{line: 228, col: 27, offset: 4439},
-								name: "Expression",
+						&zeroOrMoreExpr{
+							pos: position{line: 409, col: 17, offset: 9255},
+							expr: &charClassMatcher{
+								pos:        position{line: 409, col: 17, offset: 9255},
+								val:        "[^`]",
+								chars:      []rune{'`'},
+								ignoreCase: false,
+								inverted:   true,
 							},
 						},
-						&ruleRefExpr{
-							pos:  position{line: 228, col: 38, offset: 4450},
-							name: "_",
+						&litMatcher{
+							pos:        position{line: 409, col: 23, offset: 9261},
+							val:        "`",
+							ignoreCase: false,
+							want:       "\"`\"",
 						},
 					},
 				},
 			},
 		},
 		{
-			name: "Dict",
-			pos:  position{line: 232, col: 1, offset: 4475},
+			name: "Variable",
+			pos:  position{line: 413, col: 1, offset: 9315},
 			expr: &actionExpr{
-				pos: position{line: 232, col: 8, offset: 4482},
-				run: (*parser).callonDict1,
+				pos: position{line: 413, col: 12, offset: 9326},
+				run: (*parser).callonVariable1,
 				expr: &seqExpr{
-					pos: position{line: 232, col: 8, offset: 4482},
+					pos: position{line: 413, col: 12, offset: 9326},
 					exprs: []interface{}{
 						&litMatcher{
-							pos:        position{line: 232, col: 8, offset: 4482},
-							val:        "{",
+							pos:        position{line: 413, col: 12, offset: 9326},
+							val:        "$",
 							ignoreCase: false,
-						},
-						&ruleRefExpr{
-							pos:  position{line: 232, col: 12, offset: 4486},
-							name: "_",
-						},
-						&labeledExpr{
-							pos:   position{line: 232, col: 14, offset: 4488},
-							label: "first_",
-							expr: &seqExpr{
-								pos: position{line: 232, col: 22, offset: 4496},
-								exprs: []interface{}{
-									&ruleRefExpr{
-										pos:  position{line: 232, col: 22, offset: 4496},
-										name: "Identifier",
-									},
-									&litMatcher{
-										pos:        position{line: 232, col: 33, offset: 4507},
-										val:        ":",
-										ignoreCase: false,
-									},
-									&ruleRefExpr{
-										pos:  position{line: 232, col: 37, offset: 4511},
-										name: "_",
-									},
-									&ruleRefExpr{
-										pos:  position{line: 232, col: 39, offset: 4513},
-										name: "Expression",
-									},
-								},
-							},
-						},
-						&ruleRefExpr{
-							pos:  position{line: 232, col: 51, offset: 4525},
-							name: "_",
+							want:       "\"$\"",
 						},
 						&labeledExpr{
-							pos:   position{line: 232, col: 53, offset: 4527},
-							label: "rest_",
-							expr: &zeroOrMoreExpr{
-								pos: position{line: 232, col: 59, offset: 4533},
-								expr: &seqExpr{
-									pos: position{line: 232, col: 60, offset: 4534},
-									exprs: []interface{}{
-										&litMatcher{
-											pos:        position{line: 232, col: 60, offset: 4534},
-											val:        ",",
-											ignoreCase: false,
-										},
-										&ruleRefExpr{
-											pos:  position{line: 232, col: 64, offset: 4538},
-											name: "_",
-										},
-										&ruleRefExpr{
-											pos:  position{line: 232, col: 66, offset: 4540},
-											name: "Identifier",
-										},
-										&litMatcher{
-											pos:        position{line: 232, col: 77, offset: 4551},
-											val:        ":",
-											ignoreCase: false,
-										},
-										&ruleRefExpr{
-											pos:  position{line: 232, col: 81, offset: 4555},
-											name: "_",
-										},
-										&ruleRefExpr{
-											pos:  position{line: 232, col: 83, offset: 4557},
-											name: "Expression",
-										},
-									},
-								},
+							pos:   position{line: 413, col: 16, offset: 9330},
+							label: "name",
+							expr: &ruleRefExpr{
+								pos:  position{line: 413, col: 21, offset: 9335},
+								name: "Identifier",
 							},
 						},
-						&ruleRefExpr{
-							pos:  position{line: 232, col: 96, offset: 4570},
-							name: "_",
-						},
-						&litMatcher{
-							pos:        position{line: 232, col: 98, offset: 4572},
-							val:        "}",
-							ignoreCase: false,
-						},
 					},
 				},
 			},
 		},
 		{
 			name: "Identifier",
-			pos:  position{line: 244, col: 1, offset: 4855},
+			pos:  position{line: 417, col: 1, offset: 9409},
 			expr: &choiceExpr{
-				pos: position{line: 244, col: 14, offset: 4868},
+				pos: position{line: 417, col: 14, offset: 9422},
 				alternatives: []interface{}{
 					&actionExpr{
-						pos: position{line: 244, col: 14, offset: 4868},
+						pos: position{line: 417, col: 14, offset: 9422},
 						run: (*parser).callonIdentifier2,
 						expr: &oneOrMoreExpr{
-							pos: position{line: 244, col: 14, offset: 4868},
-							expr: &charClassMatcher{
-								pos:        position{line: 244, col: 14, offset: 4868},
-								val:        "[\\pL\\pNd_]",
-								chars:      []rune{'d', '_'},
-								classes:    []*unicode.RangeTable{rangeTable("L"), rangeTable("N")},
-								ignoreCase: false,
-								inverted:   false,
+							pos: position{line: 417, col: 14, offset: 9422},
+							expr: &ruleRefExpr{
+								pos:  position{line: 417, col: 14, offset: 9422},
+								name: "IdentChar",
 							},
 						},
 					},
 					&actionExpr{
-						pos: position{line: 246, col: 5, offset: 4938},
+						pos: position{line: 419, col: 5, offset: 9491},
 						run: (*parser).callonIdentifier5,
 						expr: &litMatcher{
-							pos:        position{line: 246, col: 5, offset: 4938},
+							pos:        position{line: 419, col: 5, offset: 9491},
 							val:        "~",
 							ignoreCase: false,
+							want:       "\"~\"",
 						},
 					},
 				},
 			},
 		},
+		{
+			name: "IdentChar",
+			pos:  position{line: 423, col: 1, offset: 9526},
+			expr: &charClassMatcher{
+				pos:        position{line: 423, col: 13, offset: 9538},
+				val:        "[\\pL\\pNd_]",
+				chars:      []rune{'d', '_'},
+				classes:    []*unicode.RangeTable{rangeTable("L"), rangeTable("N")},
+				ignoreCase: false,
+				inverted:   false,
+			},
+		},
 		{
 			name: "Bool",
-			pos:  position{line: 250, col: 1, offset: 4973},
+			pos:  position{line: 425, col: 1, offset: 9550},
 			expr: &choiceExpr{
-				pos: position{line: 250, col: 8, offset: 4980},
+				pos: position{line: 425, col: 8, offset: 9557},
 				alternatives: []interface{}{
 					&actionExpr{
-						pos: position{line: 250, col: 8, offset: 4980},
+						pos: position{line: 425, col: 8, offset: 9557},
 						run: (*parser).callonBool2,
 						expr: &litMatcher{
-							pos:        position{line: 250, col: 8, offset: 4980},
+							pos:        position{line: 425, col: 8, offset: 9557},
 							val:        "true",
 							ignoreCase: true,
+							want:       "\"true\"i",
 						},
 					},
 					&actionExpr{
-						pos: position{line: 252, col: 5, offset: 5019},
+						pos: position{line: 427, col: 5, offset: 9596},
 						run: (*parser).callonBool4,
 						expr: &litMatcher{
-							pos:        position{line: 252, col: 5, offset: 5019},
+							pos:        position{line: 427, col: 5, offset: 9596},
 							val:        "false",
 							ignoreCase: true,
+							want:       "\"false\"i",
 						},
 					},
 				},
@@ -986,52 +2167,173 @@ var g = &grammar{
 		},
 		{
 			name: "None",
-			pos:  position{line: 256, col: 1, offset: 5059},
+			pos:  position{line: 431, col: 1, offset: 9636},
 			expr: &actionExpr{
-				pos: position{line: 256, col: 8, offset: 5066},
+				pos: position{line: 431, col: 8, offset: 9643},
 				run: (*parser).callonNone1,
 				expr: &litMatcher{
-					pos:        position{line: 256, col: 8, offset: 5066},
+					pos:        position{line: 431, col: 8, offset: 9643},
 					val:        "none",
 					ignoreCase: true,
+					want:       "\"none\"i",
 				},
 			},
 		},
 		{
-			name: "Number",
-			pos:  position{line: 260, col: 1, offset: 5100},
+			name: "Date",
+			pos:  position{line: 435, col: 1, offset: 9677},
 			expr: &actionExpr{
-				pos: position{line: 260, col: 10, offset: 5109},
-				run: (*parser).callonNumber1,
+				pos: position{line: 435, col: 8, offset: 9684},
+				run: (*parser).callonDate1,
 				expr: &seqExpr{
-					pos: position{line: 260, col: 10, offset: 5109},
+					pos: position{line: 435, col: 8, offset: 9684},
 					exprs: []interface{}{
-						&zeroOrOneExpr{
-							pos: position{line: 260, col: 10, offset: 5109},
-							expr: &litMatcher{
-								pos:        position{line: 260, col: 10, offset: 5109},
-								val:        "-",
+						&litMatcher{
+							pos:        position{line: 435, col: 8, offset: 9684},
+							val:        "d",
+							ignoreCase: false,
+							want:       "\"d\"",
+						},
+						&labeledExpr{
+							pos:   position{line: 435, col: 12, offset: 9688},
+							label: "s",
+							expr: &ruleRefExpr{
+								pos:  position{line: 435, col: 14, offset: 9690},
+								name: "String",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Duration",
+			pos:  position{line: 439, col: 1, offset: 9741},
+			expr: &actionExpr{
+				pos: position{line: 439, col: 12, offset: 9752},
+				run: (*parser).callonDuration1,
+				expr: &seqExpr{
+					pos: position{line: 439, col: 12, offset: 9752},
+					exprs: []interface{}{
+						&oneOrMoreExpr{
+							pos: position{line: 439, col: 12, offset: 9752},
+							expr: &ruleRefExpr{
+								pos:  position{line: 439, col: 12, offset: 9752},
+								name: "DurationPart",
+							},
+						},
+						&notExpr{
+							pos: position{line: 439, col: 26, offset: 9766},
+							expr: &ruleRefExpr{
+								pos:  position{line: 439, col: 27, offset: 9767},
+								name: "IdentChar",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "DurationPart",
+			pos:  position{line: 443, col: 1, offset: 9820},
+			expr: &seqExpr{
+				pos: position{line: 443, col: 16, offset: 9835},
+				exprs: []interface{}{
+					&oneOrMoreExpr{
+						pos: position{line: 443, col: 16, offset: 9835},
+						expr: &charClassMatcher{
+							pos:        position{line: 443, col: 16, offset: 9835},
+							val:        "[0-9]",
+							ranges:     []rune{'0', '9'},
+							ignoreCase: false,
+							inverted:   false,
+						},
+					},
+					&zeroOrOneExpr{
+						pos: position{line: 443, col: 23, offset: 9842},
+						expr: &seqExpr{
+							pos: position{line: 443, col: 24, offset: 9843},
+							exprs: []interface{}{
+								&litMatcher{
+									pos:        position{line: 443, col: 24, offset: 9843},
+									val:        ".",
+									ignoreCase: false,
+									want:       "\".\"",
+								},
+								&oneOrMoreExpr{
+									pos: position{line: 443, col: 28, offset: 9847},
+									expr: &charClassMatcher{
+										pos:        position{line: 443, col: 28, offset: 9847},
+										val:        "[0-9]",
+										ranges:     []rune{'0', '9'},
+										ignoreCase: false,
+										inverted:   false,
+									},
+								},
+							},
+						},
+					},
+					&choiceExpr{
+						pos: position{line: 443, col: 38, offset: 9857},
+						alternatives: []interface{}{
+							&litMatcher{
+								pos:        position{line: 443, col: 38, offset: 9857},
+								val:        "ms",
 								ignoreCase: false,
+								want:       "\"ms\"",
+							},
+							&litMatcher{
+								pos:        position{line: 443, col: 45, offset: 9864},
+								val:        "us",
+								ignoreCase: false,
+								want:       "\"us\"",
+							},
+							&litMatcher{
+								pos:        position{line: 443, col: 52, offset: 9871},
+								val:        "ns",
+								ignoreCase: false,
+								want:       "\"ns\"",
+							},
+							&charClassMatcher{
+								pos:        position{line: 443, col: 59, offset: 9878},
+								val:        "[wdhms]",
+								chars:      []rune{'w', 'd', 'h', 'm', 's'},
+								ignoreCase: false,
+								inverted:   false,
 							},
 						},
+					},
+				},
+			},
+		},
+		{
+			name: "Number",
+			pos:  position{line: 445, col: 1, offset: 9888},
+			expr: &actionExpr{
+				pos: position{line: 445, col: 10, offset: 9897},
+				run: (*parser).callonNumber1,
+				expr: &seqExpr{
+					pos: position{line: 445, col: 10, offset: 9897},
+					exprs: []interface{}{
 						&ruleRefExpr{
-							pos:  position{line: 260, col: 15, offset: 5114},
+							pos:  position{line: 445, col: 10, offset: 9897},
 							name: "Integer",
 						},
 						&zeroOrOneExpr{
-							pos: position{line: 260, col: 23, offset: 5122},
+							pos: position{line: 445, col: 18, offset: 9905},
 							expr: &seqExpr{
-								pos: position{line: 260, col: 25, offset: 5124},
+								pos: position{line: 445, col: 20, offset: 9907},
 								exprs: []interface{}{
 									&litMatcher{
-										pos:        position{line: 260, col: 25, offset: 5124},
+										pos:        position{line: 445, col: 20, offset: 9907},
 										val:        ".",
 										ignoreCase: false,
+										want:       "\".\"",
 									},
 									&oneOrMoreExpr{
-										pos: position{line: 260, col: 29, offset: 5128},
+										pos: position{line: 445, col: 24, offset: 9911},
 										expr: &ruleRefExpr{
-											pos:  position{line: 260, col: 29, offset: 5128},
+											pos:  position{line: 445, col: 24, offset: 9911},
 											name: "DecimalDigit",
 										},
 									},
@@ -1039,9 +2341,9 @@ var g = &grammar{
 							},
 						},
 						&zeroOrOneExpr{
-							pos: position{line: 260, col: 46, offset: 5145},
+							pos: position{line: 445, col: 41, offset: 9928},
 							expr: &ruleRefExpr{
-								pos:  position{line: 260, col: 46, offset: 5145},
+								pos:  position{line: 445, col: 41, offset: 9928},
 								name: "Exponent",
 							},
 						},
@@ -1051,26 +2353,27 @@ var g = &grammar{
 		},
 		{
 			name: "Integer",
-			pos:  position{line: 265, col: 1, offset: 5240},
+			pos:  position{line: 450, col: 1, offset: 10023},
 			expr: &choiceExpr{
-				pos: position{line: 265, col: 11, offset: 5250},
+				pos: position{line: 450, col: 11, offset: 10033},
 				alternatives: []interface{}{
 					&litMatcher{
-						pos:        position{line: 265, col: 11, offset: 5250},
+						pos:        position{line: 450, col: 11, offset: 10033},
 						val:        "0",
 						ignoreCase: false,
+						want:       "\"0\"",
 					},
 					&seqExpr{
-						pos: position{line: 265, col: 17, offset: 5256},
+						pos: position{line: 450, col: 17, offset: 10039},
 						exprs: []interface{}{
 							&ruleRefExpr{
-								pos:  position{line: 265, col: 17, offset: 5256},
+								pos:  position{line: 450, col: 17, offset: 10039},
 								name: "NonZeroDecimalDigit",
 							},
 							&zeroOrMoreExpr{
-								pos: position{line: 265, col: 37, offset: 5276},
+								pos: position{line: 450, col: 37, offset: 10059},
 								expr: &ruleRefExpr{
-									pos:  position{line: 265, col: 37, offset: 5276},
+									pos:  position{line: 450, col: 37, offset: 10059},
 									name: "DecimalDigit",
 								},
 							},
@@ -1081,19 +2384,20 @@ var g = &grammar{
 		},
 		{
 			name: "Exponent",
-			pos:  position{line: 267, col: 1, offset: 5291},
+			pos:  position{line: 452, col: 1, offset: 10074},
 			expr: &seqExpr{
-				pos: position{line: 267, col: 12, offset: 5302},
+				pos: position{line: 452, col: 12, offset: 10085},
 				exprs: []interface{}{
 					&litMatcher{
-						pos:        position{line: 267, col: 12, offset: 5302},
+						pos:        position{line: 452, col: 12, offset: 10085},
 						val:        "e",
 						ignoreCase: true,
+						want:       "\"e\"i",
 					},
 					&zeroOrOneExpr{
-						pos: position{line: 267, col: 17, offset: 5307},
+						pos: position{line: 452, col: 17, offset: 10090},
 						expr: &charClassMatcher{
-							pos:        position{line: 267, col: 17, offset: 5307},
+							pos:        position{line: 452, col: 17, offset: 10090},
 							val:        "[+-]",
 							chars:      []rune{'+', '-'},
 							ignoreCase: false,
@@ -1101,9 +2405,9 @@ var g = &grammar{
 						},
 					},
 					&oneOrMoreExpr{
-						pos: position{line: 267, col: 23, offset: 5313},
+						pos: position{line: 452, col: 23, offset: 10096},
 						expr: &ruleRefExpr{
-							pos:  position{line: 267, col: 23, offset: 5313},
+							pos:  position{line: 452, col: 23, offset: 10096},
 							name: "DecimalDigit",
 						},
 					},
@@ -1112,9 +2416,9 @@ var g = &grammar{
 		},
 		{
 			name: "DecimalDigit",
-			pos:  position{line: 269, col: 1, offset: 5328},
+			pos:  position{line: 454, col: 1, offset: 10111},
 			expr: &charClassMatcher{
-				pos:        position{line: 269, col: 16, offset: 5343},
+				pos:        position{line: 454, col: 16, offset: 10126},
 				val:        "[0-9]",
 				ranges:     []rune{'0', '9'},
 				ignoreCase: false,
@@ -1123,9 +2427,9 @@ var g = &grammar{
 		},
 		{
 			name: "NonZeroDecimalDigit",
-			pos:  position{line: 271, col: 1, offset: 5350},
+			pos:  position{line: 456, col: 1, offset: 10133},
 			expr: &charClassMatcher{
-				pos:        position{line: 271, col: 23, offset: 5372},
+				pos:        position{line: 456, col: 23, offset: 10155},
 				val:        "[1-9]",
 				ranges:     []rune{'1', '9'},
 				ignoreCase: false,
@@ -1134,48 +2438,50 @@ var g = &grammar{
 		},
 		{
 			name: "String",
-			pos:  position{line: 273, col: 1, offset: 5379},
+			pos:  position{line: 458, col: 1, offset: 10162},
 			expr: &actionExpr{
-				pos: position{line: 273, col: 10, offset: 5388},
+				pos: position{line: 458, col: 10, offset: 10171},
 				run: (*parser).callonString1,
 				expr: &seqExpr{
-					pos: position{line: 273, col: 10, offset: 5388},
+					pos: position{line: 458, col: 10, offset: 10171},
 					exprs: []interface{}{
 						&litMatcher{
-							pos:        position{line: 273, col: 10, offset: 5388},
+							pos:        position{line: 458, col: 10, offset: 10171},
 							val:        "\"",
 							ignoreCase: false,
+							want:       "\"\\\"\"",
 						},
 						&zeroOrMoreExpr{
-							pos: position{line: 273, col: 14, offset: 5392},
+							pos: position{line: 458, col: 14, offset: 10175},
 							expr: &choiceExpr{
-								pos: position{line: 273, col: 16, offset: 5394},
+								pos: position{line: 458, col: 16, offset: 10177},
 								alternatives: []interface{}{
 									&seqExpr{
-										pos: position{line: 273, col: 16, offset: 5394},
+										pos: position{line: 458, col: 16, offset: 10177},
 										exprs: []interface{}{
 											&notExpr{
-												pos: position{line: 273, col: 16, offset: 5394},
+												pos: position{line: 458, col: 16, offset: 10177},
 												expr: &ruleRefExpr{
-													pos:  position{line: 273, col: 17, offset: 5395},
+													pos:  position{line: 458, col: 17, offset: 10178},
 													name: "EscapedChar",
 												},
 											},
 											&anyMatcher{
-												line: 273, col: 29, offset: 5407,
+												line: 458, col: 29, offset: 10190,
 											},
 										},
 									},
 									&seqExpr{
-										pos: position{line: 273, col: 33, offset: 5411},
+										pos: position{line: 458, col: 33, offset: 10194},
 										exprs: []interface{}{
 											&litMatcher{
-												pos:        position{line: 273, col: 33, offset: 5411},
+												pos:        position{line: 458, col: 33, offset: 10194},
 												val:        "\\",
 												ignoreCase: false,
+												want:       "\"\\\\\"",
 											},
 											&ruleRefExpr{
-												pos:  position{line: 273, col: 38, offset: 5416},
+												pos:  position{line: 458, col: 38, offset: 10199},
 												name: "EscapeSequence",
 											},
 										},
@@ -1184,9 +2490,10 @@ var g = &grammar{
 							},
 						},
 						&litMatcher{
-							pos:        position{line: 273, col: 56, offset: 5434},
+							pos:        position{line: 458, col: 56, offset: 10217},
 							val:        "\"",
 							ignoreCase: false,
+							want:       "\"\\\"\"",
 						},
 					},
 				},
@@ -1194,9 +2501,9 @@ var g = &grammar{
 		},
 		{
 			name: "EscapedChar",
-			pos:  position{line: 278, col: 1, offset: 5513},
+			pos:  position{line: 463, col: 1, offset: 10296},
 			expr: &charClassMatcher{
-				pos:        position{line: 278, col: 15, offset: 5527},
+				pos:        position{line: 463, col: 15, offset: 10310},
 				val:        "[\\x00-\\x1f\"\\\\]",
 				chars:      []rune{'"', '\\'},
 				ranges:     []rune{'\x00', '\x1f'},
@@ -1206,16 +2513,16 @@ var g = &grammar{
 		},
 		{
 			name: "EscapeSequence",
-			pos:  position{line: 280, col: 1, offset: 5543},
+			pos:  position{line: 465, col: 1, offset: 10326},
 			expr: &choiceExpr{
-				pos: position{line: 280, col: 18, offset: 5560},
+				pos: position{line: 465, col: 18, offset: 10343},
 				alternatives: []interface{}{
 					&ruleRefExpr{
-						pos:  position{line: 280, col: 18, offset: 5560},
+						pos:  position{line: 465, col: 18, offset: 10343},
 						name: "SingleCharEscape",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 280, col: 37, offset: 5579},
+						pos:  position{line: 465, col: 37, offset: 10362},
 						name: "UnicodeEscape",
 					},
 				},
@@ -1223,9 +2530,9 @@ var g = &grammar{
 		},
 		{
 			name: "SingleCharEscape",
-			pos:  position{line: 282, col: 1, offset: 5594},
+			pos:  position{line: 467, col: 1, offset: 10377},
 			expr: &charClassMatcher{
-				pos:        position{line: 282, col: 20, offset: 5613},
+				pos:        position{line: 467, col: 20, offset: 10396},
 				val:        "[\"\\\\/bfnrt]",
 				chars:      []rune{'"', '\\', '/', 'b', 'f', 'n', 'r', 't'},
 				ignoreCase: false,
@@ -1234,29 +2541,30 @@ var g = &grammar{
 		},
 		{
 			name: "UnicodeEscape",
-			pos:  position{line: 284, col: 1, offset: 5626},
+			pos:  position{line: 469, col: 1, offset: 10409},
 			expr: &seqExpr{
-				pos: position{line: 284, col: 17, offset: 5642},
+				pos: position{line: 469, col: 17, offset: 10425},
 				exprs: []interface{}{
 					&litMatcher{
-						pos:        position{line: 284, col: 17, offset: 5642},
+						pos:        position{line: 469, col: 17, offset: 10425},
 						val:        "u",
 						ignoreCase: false,
+						want:       "\"u\"",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 284, col: 21, offset: 5646},
+						pos:  position{line: 469, col: 21, offset: 10429},
 						name: "HexDigit",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 284, col: 30, offset: 5655},
+						pos:  position{line: 469, col: 30, offset: 10438},
 						name: "HexDigit",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 284, col: 39, offset: 5664},
+						pos:  position{line: 469, col: 39, offset: 10447},
 						name: "HexDigit",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 284, col: 48, offset: 5673},
+						pos:  position{line: 469, col: 48, offset: 10456},
 						name: "HexDigit",
 					},
 				},
@@ -1264,9 +2572,9 @@ var g = &grammar{
 		},
 		{
 			name: "HexDigit",
-			pos:  position{line: 286, col: 1, offset: 5683},
+			pos:  position{line: 471, col: 1, offset: 10466},
 			expr: &charClassMatcher{
-				pos:        position{line: 286, col: 12, offset: 5694},
+				pos:        position{line: 471, col: 12, offset: 10477},
 				val:        "[0-9a-f]i",
 				ranges:     []rune{'0', '9', 'a', 'f'},
 				ignoreCase: true,
@@ -1275,28 +2583,36 @@ var g = &grammar{
 		},
 		{
 			name: "Selector",
-			pos:  position{line: 290, col: 1, offset: 5719},
+			pos:  position{line: 475, col: 1, offset: 10502},
 			expr: &choiceExpr{
-				pos: position{line: 290, col: 12, offset: 5730},
+				pos: position{line: 475, col: 12, offset: 10513},
 				alternatives: []interface{}{
 					&ruleRefExpr{
-						pos:  position{line: 290, col: 12, offset: 5730},
+						pos:  position{line: 475, col: 12, offset: 10513},
 						name: "Recurse",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 290, col: 22, offset: 5740},
+						pos:  position{line: 475, col: 22, offset: 10523},
 						name: "Relative",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 290, col: 33, offset: 5751},
+						pos:  position{line: 475, col: 33, offset: 10534},
 						name: "Dir",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 290, col: 39, offset: 5757},
+						pos:  position{line: 475, col: 39, offset: 10540},
+						name: "ObjectID",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 475, col: 50, offset: 10551},
 						name: "Pattern",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 290, col: 49, offset: 5767},
+						pos:  position{line: 475, col: 60, offset: 10561},
+						name: "TagFilter",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 475, col: 72, offset: 10573},
 						name: "Filter",
 					},
 				},
@@ -1304,26 +2620,27 @@ var g = &grammar{
 		},
 		{
 			name: "Tail",
-			pos:  position{line: 292, col: 1, offset: 5775},
+			pos:  position{line: 477, col: 1, offset: 10581},
 			expr: &choiceExpr{
-				pos: position{line: 292, col: 8, offset: 5782},
+				pos: position{line: 477, col: 8, offset: 10588},
 				alternatives: []interface{}{
 					&actionExpr{
-						pos: position{line: 292, col: 8, offset: 5782},
+						pos: position{line: 477, col: 8, offset: 10588},
 						run: (*parser).callonTail2,
 						expr: &seqExpr{
-							pos: position{line: 292, col: 8, offset: 5782},
+							pos: position{line: 477, col: 8, offset: 10588},
 							exprs: []interface{}{
 								&litMatcher{
-									pos:        position{line: 292, col: 8, offset: 5782},
+									pos:        position{line: 477, col: 8, offset: 10588},
 									val:        "|",
 									ignoreCase: false,
+									want:       "\"|\"",
 								},
 								&labeledExpr{
-									pos:   position{line: 292, col: 12, offset: 5786},
+									pos:   position{line: 477, col: 12, offset: 10592},
 									label: "expr",
 									expr: &ruleRefExpr{
-										pos:  position{line: 292, col: 17, offset: 5791},
+										pos:  position{line: 477, col: 17, offset: 10597},
 										name: "Expression",
 									},
 								},
@@ -1331,12 +2648,13 @@ var g = &grammar{
 						},
 					},
 					&actionExpr{
-						pos: position{line: 295, col: 5, offset: 5874},
+						pos: position{line: 480, col: 5, offset: 10680},
 						run: (*parser).callonTail7,
 						expr: &litMatcher{
-							pos:        position{line: 295, col: 5, offset: 5874},
+							pos:        position{line: 480, col: 5, offset: 10680},
 							val:        "|",
 							ignoreCase: false,
+							want:       "\"|\"",
 						},
 					},
 				},
@@ -1344,50 +2662,182 @@ var g = &grammar{
 		},
 		{
 			name: "Dir",
-			pos:  position{line: 299, col: 1, offset: 5907},
+			pos:  position{line: 484, col: 1, offset: 10713},
 			expr: &actionExpr{
-				pos: position{line: 299, col: 7, offset: 5913},
+				pos: position{line: 484, col: 7, offset: 10719},
 				run: (*parser).callonDir1,
 				expr: &labeledExpr{
-					pos:   position{line: 299, col: 7, offset: 5913},
+					pos:   position{line: 484, col: 7, offset: 10719},
 					label: "dirs_",
 					expr: &oneOrMoreExpr{
-						pos: position{line: 299, col: 13, offset: 5919},
+						pos: position{line: 484, col: 13, offset: 10725},
 						expr: &litMatcher{
-							pos:        position{line: 299, col: 13, offset: 5919},
+							pos:        position{line: 484, col: 13, offset: 10725},
 							val:        "/",
 							ignoreCase: false,
+							want:       "\"/\"",
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "ObjectID",
+			pos:  position{line: 488, col: 1, offset: 10783},
+			expr: &actionExpr{
+				pos: position{line: 488, col: 12, offset: 10794},
+				run: (*parser).callonObjectID1,
+				expr: &seqExpr{
+					pos: position{line: 488, col: 12, offset: 10794},
+					exprs: []interface{}{
+						&litMatcher{
+							pos:        position{line: 488, col: 12, offset: 10794},
+							val:        "#",
+							ignoreCase: false,
+							want:       "\"#\"",
+						},
+						&oneOrMoreExpr{
+							pos: position{line: 488, col: 16, offset: 10798},
+							expr: &charClassMatcher{
+								pos:        position{line: 488, col: 16, offset: 10798},
+								val:        "[0-9a-f-]i",
+								chars:      []rune{'-'},
+								ranges:     []rune{'0', '9', 'a', 'f'},
+								ignoreCase: true,
+								inverted:   false,
+							},
+						},
+						&andExpr{
+							pos: position{line: 488, col: 28, offset: 10810},
+							expr: &ruleRefExpr{
+								pos:  position{line: 488, col: 29, offset: 10811},
+								name: "OpStop",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "TagFilter",
+			pos:  position{line: 492, col: 1, offset: 10884},
+			expr: &actionExpr{
+				pos: position{line: 492, col: 13, offset: 10896},
+				run: (*parser).callonTagFilter1,
+				expr: &seqExpr{
+					pos: position{line: 492, col: 13, offset: 10896},
+					exprs: []interface{}{
+						&litMatcher{
+							pos:        position{line: 492, col: 13, offset: 10896},
+							val:        "(#",
+							ignoreCase: false,
+							want:       "\"(#\"",
+						},
+						&labeledExpr{
+							pos:   position{line: 492, col: 18, offset: 10901},
+							label: "first",
+							expr: &ruleRefExpr{
+								pos:  position{line: 492, col: 24, offset: 10907},
+								name: "Tag",
+							},
+						},
+						&labeledExpr{
+							pos:   position{line: 492, col: 28, offset: 10911},
+							label: "rest_",
+							expr: &zeroOrMoreExpr{
+								pos: position{line: 492, col: 34, offset: 10917},
+								expr: &seqExpr{
+									pos: position{line: 492, col: 35, offset: 10918},
+									exprs: []interface{}{
+										&ruleRefExpr{
+											pos:  position{line: 492, col: 35, offset: 10918},
+											name: "_",
+										},
+										&litMatcher{
+											pos:        position{line: 492, col: 37, offset: 10920},
+											val:        ",",
+											ignoreCase: false,
+											want:       "\",\"",
+										},
+										&ruleRefExpr{
+											pos:  position{line: 492, col: 41, offset: 10924},
+											name: "_",
+										},
+										&litMatcher{
+											pos:        position{line: 492, col: 43, offset: 10926},
+											val:        "#",
+											ignoreCase: false,
+											want:       "\"#\"",
+										},
+										&ruleRefExpr{
+											pos:  position{line: 492, col: 47, offset: 10930},
+											name: "Tag",
+										},
+									},
+								},
+							},
+						},
+						&ruleRefExpr{
+							pos:  position{line: 492, col: 53, offset: 10936},
+							name: "_",
+						},
+						&litMatcher{
+							pos:        position{line: 492, col: 55, offset: 10938},
+							val:        ")",
+							ignoreCase: false,
+							want:       "\")\"",
 						},
 					},
 				},
 			},
 		},
+		{
+			name: "Tag",
+			pos:  position{line: 500, col: 1, offset: 11130},
+			expr: &actionExpr{
+				pos: position{line: 500, col: 7, offset: 11136},
+				run: (*parser).callonTag1,
+				expr: &oneOrMoreExpr{
+					pos: position{line: 500, col: 7, offset: 11136},
+					expr: &charClassMatcher{
+						pos:        position{line: 500, col: 7, offset: 11136},
+						val:        "[\\pL\\pNd_-]",
+						chars:      []rune{'d', '_', '-'},
+						classes:    []*unicode.RangeTable{rangeTable("L"), rangeTable("N")},
+						ignoreCase: false,
+						inverted:   false,
+					},
+				},
+			},
+		},
 		{
 			name: "Filter",
-			pos:  position{line: 303, col: 1, offset: 5977},
+			pos:  position{line: 504, col: 1, offset: 11183},
 			expr: &actionExpr{
-				pos: position{line: 303, col: 10, offset: 5986},
+				pos: position{line: 504, col: 10, offset: 11192},
 				run: (*parser).callonFilter1,
 				expr: &seqExpr{
-					pos: position{line: 303, col: 10, offset: 5986},
+					pos: position{line: 504, col: 10, offset: 11192},
 					exprs: []interface{}{
 						&litMatcher{
-							pos:        position{line: 303, col: 10, offset: 5986},
+							pos:        position{line: 504, col: 10, offset: 11192},
 							val:        "(?",
 							ignoreCase: false,
+							want:       "\"(?\"",
 						},
 						&labeledExpr{
-							pos:   position{line: 303, col: 15, offset: 5991},
+							pos:   position{line: 504, col: 15, offset: 11197},
 							label: "expr",
 							expr: &ruleRefExpr{
-								pos:  position{line: 303, col: 20, offset: 5996},
+								pos:  position{line: 504, col: 20, offset: 11202},
 								name: "Expression",
 							},
 						},
 						&litMatcher{
-							pos:        position{line: 303, col: 31, offset: 6007},
+							pos:        position{line: 504, col: 31, offset: 11213},
 							val:        ")",
 							ignoreCase: false,
+							want:       "\")\"",
 						},
 					},
 				},
@@ -1395,29 +2845,30 @@ var g = &grammar{
 		},
 		{
 			name: "Relative",
-			pos:  position{line: 307, col: 1, offset: 6060},
+			pos:  position{line: 508, col: 1, offset: 11266},
 			expr: &actionExpr{
-				pos: position{line: 307, col: 12, offset: 6071},
+				pos: position{line: 508, col: 12, offset: 11277},
 				run: (*parser).callonRelative1,
 				expr: &seqExpr{
-					pos: position{line: 307, col: 12, offset: 6071},
+					pos: position{line: 508, col: 12, offset: 11277},
 					exprs: []interface{}{
 						&labeledExpr{
-							pos:   position{line: 307, col: 12, offset: 6071},
+							pos:   position{line: 508, col: 12, offset: 11277},
 							label: "rel_",
 							expr: &oneOrMoreExpr{
-								pos: position{line: 307, col: 17, offset: 6076},
+								pos: position{line: 508, col: 17, offset: 11282},
 								expr: &litMatcher{
-									pos:        position{line: 307, col: 17, offset: 6076},
+									pos:        position{line: 508, col: 17, offset: 11282},
 									val:        ".",
 									ignoreCase: false,
+									want:       "\".\"",
 								},
 							},
 						},
 						&andExpr{
-							pos: position{line: 307, col: 22, offset: 6081},
+							pos: position{line: 508, col: 22, offset: 11287},
 							expr: &ruleRefExpr{
-								pos:  position{line: 307, col: 23, offset: 6082},
+								pos:  position{line: 508, col: 23, offset: 11288},
 								name: "OpStop",
 							},
 						},
@@ -1427,27 +2878,28 @@ var g = &grammar{
 		},
 		{
 			name: "Recurse",
-			pos:  position{line: 312, col: 1, offset: 6153},
+			pos:  position{line: 513, col: 1, offset: 11359},
 			expr: &actionExpr{
-				pos: position{line: 312, col: 11, offset: 6163},
+				pos: position{line: 513, col: 11, offset: 11369},
 				run: (*parser).callonRecurse1,
 				expr: &litMatcher{
-					pos:        position{line: 312, col: 11, offset: 6163},
+					pos:        position{line: 513, col: 11, offset: 11369},
 					val:        "**/",
 					ignoreCase: false,
+					want:       "\"**/\"",
 				},
 			},
 		},
 		{
 			name: "Pattern",
-			pos:  position{line: 317, col: 1, offset: 6230},
+			pos:  position{line: 518, col: 1, offset: 11436},
 			expr: &actionExpr{
-				pos: position{line: 317, col: 11, offset: 6240},
+				pos: position{line: 518, col: 11, offset: 11446},
 				run: (*parser).callonPattern1,
 				expr: &oneOrMoreExpr{
-					pos: position{line: 317, col: 11, offset: 6240},
+					pos: position{line: 518, col: 11, offset: 11446},
 					expr: &charClassMatcher{
-						pos:        position{line: 317, col: 11, offset: 6240},
+						pos:        position{line: 518, col: 11, offset: 11446},
 						val:        "[^/()|]",
 						chars:      []rune{'/', '(', ')', '|'},
 						ignoreCase: false,
@@ -1458,54 +2910,113 @@ var g = &grammar{
 		},
 		{
 			name: "OpStop",
-			pos:  position{line: 329, col: 1, offset: 6501},
+			pos:  position{line: 530, col: 1, offset: 11707},
 			expr: &choiceExpr{
-				pos: position{line: 329, col: 10, offset: 6510},
+				pos: position{line: 530, col: 10, offset: 11716},
 				alternatives: []interface{}{
 					&litMatcher{
-						pos:        position{line: 329, col: 10, offset: 6510},
+						pos:        position{line: 530, col: 10, offset: 11716},
 						val:        "/",
 						ignoreCase: false,
+						want:       "\"/\"",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 329, col: 16, offset: 6516},
+						pos:  position{line: 530, col: 16, offset: 11722},
 						name: "EOF",
 					},
 					&litMatcher{
-						pos:        position{line: 329, col: 22, offset: 6522},
+						pos:        position{line: 530, col: 22, offset: 11728},
 						val:        "|",
 						ignoreCase: false,
+						want:       "\"|\"",
 					},
 					&litMatcher{
-						pos:        position{line: 329, col: 28, offset: 6528},
+						pos:        position{line: 530, col: 28, offset: 11734},
 						val:        ")",
 						ignoreCase: false,
+						want:       "\")\"",
+					},
+				},
+			},
+		},
+		{
+			name:        "_",
+			displayName: "\"whitespace\"",
+			pos:         position{line: 532, col: 1, offset: 11739},
+			expr: &zeroOrMoreExpr{
+				pos: position{line: 532, col: 18, offset: 11756},
+				expr: &charClassMatcher{
+					pos:        position{line: 532, col: 18, offset: 11756},
+					val:        "[ \\t\\r\\n]",
+					chars:      []rune{' ', '\t', '\r', '\n'},
+					ignoreCase: false,
+					inverted:   false,
+				},
+			},
+		},
+		{
+			name:        "Gap",
+			displayName: "\"whitespace\"",
+			pos:         position{line: 534, col: 1, offset: 11768},
+			expr: &zeroOrMoreExpr{
+				pos: position{line: 534, col: 20, offset: 11787},
+				expr: &choiceExpr{
+					pos: position{line: 534, col: 21, offset: 11788},
+					alternatives: []interface{}{
+						&ruleRefExpr{
+							pos:  position{line: 534, col: 21, offset: 11788},
+							name: "Comment",
+						},
+						&oneOrMoreExpr{
+							pos: position{line: 534, col: 31, offset: 11798},
+							expr: &charClassMatcher{
+								pos:        position{line: 534, col: 31, offset: 11798},
+								val:        "[ \\t\\r\\n]",
+								chars:      []rune{' ', '\t', '\r', '\n'},
+								ignoreCase: false,
+								inverted:   false,
+							},
+						},
 					},
 				},
 			},
 		},
 		{
-			name:        "_",
-			displayName: "\"whitespace\"",
-			pos:         position{line: 331, col: 1, offset: 6533},
-			expr: &zeroOrMoreExpr{
-				pos: position{line: 331, col: 18, offset: 6550},
-				expr: &charClassMatcher{
-					pos:        position{line: 331, col: 18, offset: 6550},
-					val:        "[ \\t\\r\\n]",
-					chars:      []rune{' ', '\t', '\r', '\n'},
-					ignoreCase: false,
-					inverted:   false,
+			name: "Comment",
+			pos:  position{line: 538, col: 1, offset: 11886},
+			expr: &actionExpr{
+				pos: position{line: 538, col: 11, offset: 11896},
+				run: (*parser).callonComment1,
+				expr: &seqExpr{
+					pos: position{line: 538, col: 11, offset: 11896},
+					exprs: []interface{}{
+						&litMatcher{
+							pos:        position{line: 538, col: 11, offset: 11896},
+							val:        "#",
+							ignoreCase: false,
+							want:       "\"#\"",
+						},
+						&zeroOrMoreExpr{
+							pos: position{line: 538, col: 15, offset: 11900},
+							expr: &charClassMatcher{
+								pos:        position{line: 538, col: 15, offset: 11900},
+								val:        "[^\\r\\n]",
+								chars:      []rune{'\r', '\n'},
+								ignoreCase: false,
+								inverted:   true,
+							},
+						},
+					},
 				},
 			},
 		},
 		{
 			name: "EOF",
-			pos:  position{line: 333, col: 1, offset: 6562},
+			pos:  position{line: 542, col: 1, offset: 11994},
 			expr: &notExpr{
-				pos: position{line: 333, col: 7, offset: 6568},
+				pos: position{line: 542, col: 7, offset: 12000},
 				expr: &anyMatcher{
-					line: 333, col: 8, offset: 6569,
+					line: 542, col: 8, offset: 12001,
 				},
 			},
 		},
@@ -1533,7 +3044,7 @@ func (c *current) onQuery1(sels_, tail interface{}) (interface{}, error) {
 		hasTail = true
 	}
 	if length == 0 {
-		return &queryNode{&relSel{count: 1, next: last}, multi, hasTail}, nil
+		return &queryNode{sel: &relSel{count: 1, next: last}, multi: multi, tail: hasTail}, nil
 	}
 	for i := length - 1; i >= 0; i-- {
 		sel := sels[i].(selector)
@@ -1562,7 +3073,7 @@ func (c *current) onQuery1(sels_, tail interface{}) (interface{}, error) {
 		sel.setNext(last)
 		last = sel
 	}
-	return &queryNode{last, multi, hasTail}, nil
+	return &queryNode{sel: last, multi: multi, tail: hasTail}, nil
 }
 
 func (p *parser) callonQuery1() (interface{}, error) {
@@ -1571,7 +3082,144 @@ func (p *parser) callonQuery1() (interface{}, error) {
 	return p.cur.onQuery1(stack["sels_"], stack["tail"])
 }
 
-func (c *current) onExpression1(first, rest_ interface{}) (interface{}, error) {
+func (c *current) onSidecar1(lead, expr, trail interface{}) (interface{}, error) {
+	return newSidecar(c, expr.(fExpr), lead, trail), nil
+}
+
+func (p *parser) callonSidecar1() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onSidecar1(stack["lead"], stack["expr"], stack["trail"])
+}
+
+func (c *current) onExpression3() error {
+	c.state["depth"] = stateInt(c, "depth") + 1
+	return nil
+}
+
+func (p *parser) callonExpression3() error {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onExpression3()
+}
+
+func (c *current) onExpression10(expr interface{}) error {
+	c.state["depth"] = stateInt(c, "depth") - 1
+	return nil
+}
+
+func (p *parser) callonExpression10() error {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onExpression10(stack["expr"])
+}
+
+func (c *current) onExpression1(expr interface{}) (interface{}, error) {
+	return expr, nil
+}
+
+func (p *parser) callonExpression1() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onExpression1(stack["expr"])
+}
+
+func (c *current) onLet1(first, rest_, body interface{}) (interface{}, error) {
+	rest := toList(rest_)
+	node := &letNode{body: body.(fExpr)}
+	node.bindings = append(node.bindings, first.(binding))
+	for _, bnd_ := range rest {
+		node.bindings = append(node.bindings, toList(bnd_)[3].(binding))
+	}
+	return node, nil
+}
+
+func (p *parser) callonLet1() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onLet1(stack["first"], stack["rest_"], stack["body"])
+}
+
+func (c *current) onBinding12(name interface{}) error {
+	forbidIn(c, true)
+	return nil
+}
+
+func (p *parser) callonBinding12() error {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onBinding12(stack["name"])
+}
+
+func (c *current) onBinding15(name, value interface{}) error {
+	forbidIn(c, false)
+	return nil
+}
+
+func (p *parser) callonBinding15() error {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onBinding15(stack["name"], stack["value"])
+}
+
+func (c *current) onBinding1(name, value interface{}) (interface{}, error) {
+	return binding{name: name.(*attribRes).identifier, value: value.(fExpr)}, nil
+}
+
+func (p *parser) callonBinding1() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onBinding1(stack["name"], stack["value"])
+}
+
+func (c *current) onConditional1(cond, branches_ interface{}) (interface{}, error) {
+	if branches_ == nil {
+		return cond, nil
+	}
+	branches := toList(branches_)
+	return &condNode{
+		cond: cond.(fExpr),
+		then: branches[3].(fExpr),
+		els:  branches[5].(fExpr),
+	}, nil
+}
+
+func (p *parser) callonConditional1() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onConditional1(stack["cond"], stack["branches_"])
+}
+
+func (c *current) onCoalescence1(first, rest_ interface{}) (interface{}, error) {
+	left := first.(fExpr)
+	rest := toList(rest_)
+	for _, comp_ := range rest {
+		comp := toList(comp_)
+		node := comp[1].(*coalesceNode)
+		node.left = left
+		node.right = comp[3].(fExpr)
+		left = node
+	}
+	return left, nil
+}
+
+func (p *parser) callonCoalescence1() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onCoalescence1(stack["first"], stack["rest_"])
+}
+
+func (c *current) onCoalesce1() (interface{}, error) {
+	return &coalesceNode{}, nil
+}
+
+func (p *parser) callonCoalesce1() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onCoalesce1()
+}
+
+func (c *current) onDisjunction1(first, rest_ interface{}) (interface{}, error) {
 	left := first.(fExpr)
 	rest := toList(rest_)
 	for _, comp_ := range rest {
@@ -1584,10 +3232,10 @@ func (c *current) onExpression1(first, rest_ interface{}) (interface{}, error) {
 	return left, nil
 }
 
-func (p *parser) callonExpression1() (interface{}, error) {
+func (p *parser) callonDisjunction1() (interface{}, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
-	return p.cur.onExpression1(stack["first"], stack["rest_"])
+	return p.cur.onDisjunction1(stack["first"], stack["rest_"])
 }
 
 func (c *current) onOr1() (interface{}, error) {
@@ -1648,8 +3296,12 @@ func (p *parser) callonLevel_B1() (interface{}, error) {
 	return p.cur.onLevel_B1(stack["first"], stack["rest_"])
 }
 
-func (c *current) onComparison1() (interface{}, error) {
+func (c *current) onComparison2() (interface{}, error) {
 	switch string(c.text) {
+	case "=~":
+		return &compareNode{op: MATCH}, nil
+	case "!~":
+		return &compareNode{op: NMATCH}, nil
 	case "==":
 		return &compareNode{op: EQ}, nil
 	case "!=":
@@ -1664,10 +3316,40 @@ func (c *current) onComparison1() (interface{}, error) {
 	return &compareNode{op: GR}, nil
 }
 
-func (p *parser) callonComparison1() (interface{}, error) {
+func (p *parser) callonComparison2() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onComparison2()
+}
+
+func (c *current) onComparison17() (bool, error) {
+	return stateInt(c, "noIn")&(1<<uint(stateInt(c, "depth"))) == 0, nil
+}
+
+func (p *parser) callonComparison17() (bool, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onComparison17()
+}
+
+func (c *current) onComparison12() (interface{}, error) {
+	return &compareNode{op: IN}, nil
+}
+
+func (p *parser) callonComparison12() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onComparison12()
+}
+
+func (c *current) onComparison18() (interface{}, error) {
+	return &compareNode{op: NIN}, nil
+}
+
+func (p *parser) callonComparison18() (interface{}, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
-	return p.cur.onComparison1()
+	return p.cur.onComparison18()
 }
 
 func (c *current) onLevel_C1(first, rest_ interface{}) (interface{}, error) {
@@ -1719,7 +3401,7 @@ func (p *parser) callonLevel_D1() (interface{}, error) {
 }
 
 func (c *current) onMultiplicative1() (interface{}, error) {
-	return &multNode{op: c.text[0]}, nil
+	return &multNode{op: string(c.text)}, nil
 }
 
 func (p *parser) callonMultiplicative1() (interface{}, error) {
@@ -1728,17 +3410,33 @@ func (p *parser) callonMultiplicative1() (interface{}, error) {
 	return p.cur.onMultiplicative1()
 }
 
-func (c *current) onLevel_E1(op, operand interface{}) (interface{}, error) {
-	if op == nil {
-		return operand, nil
+func (c *current) onLevel_E2(op, operand interface{}) (interface{}, error) {
+	if string(op.([]byte)) == "!" {
+		return &notNode{operand.(fExpr)}, nil
+	}
+	if n, ok := operand.(fNumber); ok {
+		return -n, nil
+	}
+	return &negNode{operand.(fExpr)}, nil
+}
+
+func (p *parser) callonLevel_E2() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onLevel_E2(stack["op"], stack["operand"])
+}
+
+func (c *current) onLevel_F1(base, exponent_ interface{}) (interface{}, error) {
+	if exponent_ == nil {
+		return base, nil
 	}
-	return &notNode{operand.(fExpr)}, nil
+	return &powNode{base: base.(fExpr), exponent: toList(exponent_)[3].(fExpr)}, nil
 }
 
-func (p *parser) callonLevel_E1() (interface{}, error) {
+func (p *parser) callonLevel_F1() (interface{}, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
-	return p.cur.onLevel_E1(stack["op"], stack["operand"])
+	return p.cur.onLevel_F1(stack["base"], stack["exponent_"])
 }
 
 func (c *current) onResolution1(isRaw, first, rest_ interface{}) (interface{}, error) {
@@ -1773,6 +3471,28 @@ func (p *parser) callonResolution1() (interface{}, error) {
 	return p.cur.onResolution1(stack["isRaw"], stack["first"], stack["rest_"])
 }
 
+func (c *current) onSlice1(start, stop, step_ interface{}) (interface{}, error) {
+	node := &sliceRes{}
+	if start != nil {
+		node.start = start.(fExpr)
+	}
+	if stop != nil {
+		node.stop = stop.(fExpr)
+	}
+	if step_ != nil {
+		if step := toList(step_)[2]; step != nil {
+			node.step = step.(fExpr)
+		}
+	}
+	return node, nil
+}
+
+func (p *parser) callonSlice1() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onSlice1(stack["start"], stack["stop"], stack["step_"])
+}
+
 func (c *current) onIndex1(expr interface{}) (interface{}, error) {
 	return &indexRes{expr: expr.(fExpr)}, nil
 }
@@ -1783,18 +3503,30 @@ func (p *parser) callonIndex1() (interface{}, error) {
 	return p.cur.onIndex1(stack["expr"])
 }
 
-func (c *current) onAttribute1(identifier interface{}) (interface{}, error) {
+func (c *current) onAttribute2(identifier interface{}) (interface{}, error) {
+	return identifier, nil
+}
+
+func (p *parser) callonAttribute2() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onAttribute2(stack["identifier"])
+}
+
+func (c *current) onAttribute7(identifier interface{}) (interface{}, error) {
+	identifier.(*attribRes).optional = true
 	return identifier, nil
 }
 
-func (p *parser) callonAttribute1() (interface{}, error) {
+func (p *parser) callonAttribute7() (interface{}, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
-	return p.cur.onAttribute1(stack["identifier"])
+	return p.cur.onAttribute7(stack["identifier"])
 }
 
 func (c *current) onSubquery1(query interface{}) (interface{}, error) {
 	Log("Subquery")
+	query.(*queryNode).src = string(c.text)
 	return query, nil
 }
 
@@ -1814,48 +3546,157 @@ func (p *parser) callonCompound1() (interface{}, error) {
 	return p.cur.onCompound1(stack["expr"])
 }
 
-func (c *current) onList1(first, rest_ interface{}) (interface{}, error) {
+func (c *current) onList2(lead interface{}) (interface{}, error) {
+	return newList(c, lead), nil
+}
+
+func (p *parser) callonList2() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onList2(stack["lead"])
+}
+
+func (c *current) onList8(lead, first, gap, rest, tail interface{}) (interface{}, error) {
+	return newList(c, lead, first, gap, rest, tail), nil
+}
+
+func (p *parser) callonList8() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onList8(stack["lead"], stack["first"], stack["gap"], stack["rest"], stack["tail"])
+}
+
+func (c *current) onDict2(lead interface{}) (interface{}, error) {
+	return newDict(c, lead), nil
+}
+
+func (p *parser) callonDict2() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onDict2(stack["lead"])
+}
+
+func (c *current) onDict8(lead, first, gap, rest, tail interface{}) (interface{}, error) {
+	return newDict(c, lead, first, gap, rest, tail), nil
+}
+
+func (p *parser) callonDict8() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onDict8(stack["lead"], stack["first"], stack["gap"], stack["rest"], stack["tail"])
+}
+
+func (c *current) onDictEntry1(key_, value interface{}) (interface{}, error) {
+	var key string
+	switch k := key_.(type) {
+	case *attribRes:
+		key = k.identifier
+	case fString:
+		key = string(k)
+	}
+	return containerItem{name: pathKey(key), key: key, value: value.(fExpr)}, nil
+}
+
+func (p *parser) callonDictEntry1() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onDictEntry1(stack["key_"], stack["value"])
+}
+
+func (c *current) onCall1(name, args_ interface{}) (interface{}, error) {
+	var args []fExpr
+	if args_ != nil {
+		args = args_.([]fExpr)
+	}
+	return &callNode{name: name.(string), args: args}, nil
+}
+
+func (p *parser) callonCall1() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onCall1(stack["name"], stack["args_"])
+}
+
+func (c *current) onFunctionName1() (interface{}, error) {
+	return string(c.text), nil
+}
+
+func (p *parser) callonFunctionName1() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onFunctionName1()
+}
+
+func (c *current) onArguments1(first, rest_ interface{}) (interface{}, error) {
 	rest := toList(rest_)
-	list := make([]fExpr, len(rest)+1)
-	list[0] = first.(fExpr)
-	for i, elm := range rest {
-		list[i+1] = elm.(fExpr)
+	args := make([]fExpr, len(rest)+1)
+	args[0] = first.(fExpr)
+	for i, arg_ := range rest {
+		args[i+1] = toList(arg_)[1].(fExpr)
 	}
-	return fList(list), nil
+	return args, nil
 }
 
-func (p *parser) callonList1() (interface{}, error) {
+func (p *parser) callonArguments1() (interface{}, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
-	return p.cur.onList1(stack["first"], stack["rest_"])
+	return p.cur.onArguments1(stack["first"], stack["rest_"])
 }
 
-func (c *current) onListElements1(expr interface{}) (interface{}, error) {
-	return expr, nil
+func (c *current) onLambda1(params, body interface{}) (interface{}, error) {
+	return &lambdaNode{params: params.([]string), body: body.(fExpr)}, nil
+}
+
+func (p *parser) callonLambda1() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onLambda1(stack["params"], stack["body"])
+}
+
+func (c *current) onLambdaParams2(param interface{}) (interface{}, error) {
+	return []string{param.(*attribRes).identifier}, nil
 }
 
-func (p *parser) callonListElements1() (interface{}, error) {
+func (p *parser) callonLambdaParams2() (interface{}, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
-	return p.cur.onListElements1(stack["expr"])
+	return p.cur.onLambdaParams2(stack["param"])
 }
 
-func (c *current) onDict1(first_, rest_ interface{}) (interface{}, error) {
-	first := toList(first_)
+func (c *current) onLambdaParams5(first, rest_ interface{}) (interface{}, error) {
 	rest := toList(rest_)
-	dict := make(map[string]fExpr)
-	dict[first[0].(*attribRes).identifier] = first[3].(fExpr)
-	for _, kvp_ := range rest {
-		kvp := toList(kvp_)
-		dict[kvp[2].(*attribRes).identifier] = kvp[5].(fExpr)
+	params := make([]string, len(rest)+1)
+	params[0] = first.(*attribRes).identifier
+	for i, param_ := range rest {
+		params[i+1] = toList(param_)[3].(*attribRes).identifier
 	}
-	return fDict(dict), nil
+	return params, nil
+}
+
+func (p *parser) callonLambdaParams5() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onLambdaParams5(stack["first"], stack["rest_"])
+}
+
+func (c *current) onReference1() (interface{}, error) {
+	return fRef(c.text[1 : len(c.text)-1]), nil
+}
+
+func (p *parser) callonReference1() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onReference1()
+}
+
+func (c *current) onVariable1(name interface{}) (interface{}, error) {
+	return &varNode{name: name.(*attribRes).identifier}, nil
 }
 
-func (p *parser) callonDict1() (interface{}, error) {
+func (p *parser) callonVariable1() (interface{}, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
-	return p.cur.onDict1(stack["first_"], stack["rest_"])
+	return p.cur.onVariable1(stack["name"])
 }
 
 func (c *current) onIdentifier2() (interface{}, error) {
@@ -1908,6 +3749,26 @@ func (p *parser) callonNone1() (interface{}, error) {
 	return p.cur.onNone1()
 }
 
+func (c *current) onDate1(s interface{}) (interface{}, error) {
+	return parseDate(string(s.(fString)))
+}
+
+func (p *parser) callonDate1() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onDate1(stack["s"])
+}
+
+func (c *current) onDuration1() (interface{}, error) {
+	return parseDuration(string(c.text))
+}
+
+func (p *parser) callonDuration1() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onDuration1()
+}
+
 func (c *current) onNumber1() (interface{}, error) {
 	n, err := strconv.ParseFloat(string(c.text), 64)
 	return fNumber(n), err
@@ -1961,6 +3822,40 @@ func (p *parser) callonDir1() (interface{}, error) {
 	return p.cur.onDir1(stack["dirs_"])
 }
 
+func (c *current) onObjectID1() (interface{}, error) {
+	return &idSel{id: strings.ToLower(string(c.text[1:]))}, nil
+}
+
+func (p *parser) callonObjectID1() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onObjectID1()
+}
+
+func (c *current) onTagFilter1(first, rest_ interface{}) (interface{}, error) {
+	args := []fExpr{first.(fExpr)}
+	for _, t := range toList(rest_) {
+		args = append(args, toList(t)[4].(fExpr))
+	}
+	return &filterSel{expr: &callNode{name: "tagged", args: args}}, nil
+}
+
+func (p *parser) callonTagFilter1() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onTagFilter1(stack["first"], stack["rest_"])
+}
+
+func (c *current) onTag1() (interface{}, error) {
+	return fString(c.text), nil
+}
+
+func (p *parser) callonTag1() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onTag1()
+}
+
 func (c *current) onFilter1(expr interface{}) (interface{}, error) {
 	return &filterSel{expr: expr.(fExpr)}, nil
 }
@@ -2011,6 +3906,16 @@ func (p *parser) callonPattern1() (interface{}, error) {
 	return p.cur.onPattern1()
 }
 
+func (c *current) onComment1() (interface{}, error) {
+	return commentTok{strings.TrimRight(string(c.text), " \t"), c.pos.offset}, nil
+}
+
+func (p *parser) callonComment1() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onComment1()
+}
+
 var (
 	// errNoRule is returned when the grammar to parse has no rule.
 	errNoRule = errors.New("grammar has no rule")
@@ -2069,18 +3974,17 @@ func Entrypoint(ruleName string) Option {
 //
 // Example usage:
 //
-//     input := "input"
-//     stats := Stats{}
-//     _, err := Parse("input-file", []byte(input), Statistics(&stats, "no match"))
-//     if err != nil {
-//         log.Panicln(err)
-//     }
-//     b, err := json.MarshalIndent(stats.ChoiceAltCnt, "", "  ")
-//     if err != nil {
-//         log.Panicln(err)
-//     }
-//     fmt.Println(string(b))
-//
+//	input := "input"
+//	stats := Stats{}
+//	_, err := Parse("input-file", []byte(input), Statistics(&stats, "no match"))
+//	if err != nil {
+//	    log.Panicln(err)
+//	}
+//	b, err := json.MarshalIndent(stats.ChoiceAltCnt, "", "  ")
+//	if err != nil {
+//	    log.Panicln(err)
+//	}
+//	fmt.Println(string(b))
 func Statistics(stats *Stats, choiceNoMatch string) Option {
 	return func(p *parser) Option {
 		oldStats := p.Stats
@@ -2205,7 +4109,7 @@ type position struct {
 }
 
 func (p position) String() string {
-	return fmt.Sprintf("%d:%d [%d]", p.line, p.col, p.offset)
+	return strconv.Itoa(p.line) + ":" + strconv.Itoa(p.col) + " [" + strconv.Itoa(p.offset) + "]"
 }
 
 // savepoint stores all state required to go back to this point in the
@@ -2318,6 +4222,7 @@ type litMatcher struct {
 	pos        position
 	val        string
 	ignoreCase bool
+	want       string
 }
 
 type charClassMatcher struct {
@@ -2669,13 +4574,24 @@ type Cloner interface {
 	Clone() interface{}
 }
 
+var statePool = &sync.Pool{
+	New: func() interface{} { return make(storeDict) },
+}
+
+func (sd storeDict) Discard() {
+	for k := range sd {
+		delete(sd, k)
+	}
+	statePool.Put(sd)
+}
+
 // clone and return parser current state.
 func (p *parser) cloneState() storeDict {
 	if p.debug {
 		defer p.out(p.in("cloneState"))
 	}
 
-	state := make(storeDict, len(p.cur.state))
+	state := statePool.Get().(storeDict)
 	for k, v := range p.cur.state {
 		if c, ok := v.(Cloner); ok {
 			state[k] = c.Clone()
@@ -2692,6 +4608,7 @@ func (p *parser) restoreState(state storeDict) {
 	if p.debug {
 		defer p.out(p.in("restoreState"))
 	}
+	p.cur.state.Discard()
 	p.cur.state = state
 }
 
@@ -2804,7 +4721,7 @@ func listJoin(list []string, sep string, lastSep string) string {
 	case 1:
 		return list[0]
 	default:
-		return fmt.Sprintf("%s %s %s", strings.Join(list[:len(list)-1], sep), lastSep, list[len(list)-1])
+		return strings.Join(list[:len(list)-1], sep) + " " + lastSep + " " + list[len(list)-1]
 	}
 }
 
@@ -3100,11 +5017,6 @@ func (p *parser) parseLitMatcher(lit *litMatcher) (interface{}, bool) {
 		defer p.out(p.in("parseLitMatcher"))
 	}
 
-	ignoreCase := ""
-	if lit.ignoreCase {
-		ignoreCase = "i"
-	}
-	val := fmt.Sprintf("%q%s", lit.val, ignoreCase)
 	start := p.pt
 	for _, want := range lit.val {
 		cur := p.pt.rn
@@ -3112,13 +5024,13 @@ func (p *parser) parseLitMatcher(lit *litMatcher) (interface{}, bool) {
 			cur = unicode.ToLower(cur)
 		}
 		if cur != want {
-			p.failAt(false, start.position, val)
+			p.failAt(false, start.position, lit.want)
 			p.restore(start)
 			return nil, false
 		}
 		p.read()
 	}
-	p.failAt(true, start.position, val)
+	p.failAt(true, start.position, lit.want)
 	return p.sliceFrom(start), true
 }
 
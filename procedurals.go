@@ -2,8 +2,14 @@ package feta
 
 type objProc struct{}
 
+type defaultedProc struct{}
+
+type historyProc struct{}
+
 var procedurals fDict = fDict{
-	"obj": &objProc{},
+	"obj":       &objProc{},
+	"defaulted": &defaultedProc{},
+	"history":   &historyProc{},
 }
 
 func (node *objProc) eval(ctx *context) fExpr {
@@ -17,3 +23,41 @@ func (node *objProc) eval(ctx *context) fExpr {
 		"size":  fNumber(fi.Size()),
 	}
 }
+
+func (node *defaultedProc) eval(ctx *context) fExpr {
+	if _, err := ctx.obj.getMeta(); err != nil {
+		return fError{err.Error()}
+	}
+	res := fList{}
+	for _, k := range ctx.obj.defaulted {
+		res = append(res, fString(k))
+	}
+	return res
+}
+
+func (node *historyProc) eval(ctx *context) fExpr {
+	records, err := readHistory()
+	if err != nil {
+		return fError{err.Error()}
+	}
+	path := ctx.obj.fetaPath()
+	res := fList{}
+	for _, rec := range records {
+		if rec.Obj != path {
+			continue
+		}
+		old, new, err := rec.values()
+		if err != nil {
+			return fError{err.Error()}
+		}
+		res = append(res, fDict{
+			"change": fNumber(rec.Change),
+			"time":   fString(rec.Time),
+			"user":   fString(rec.User),
+			"path":   fString(rec.Path),
+			"old":    old,
+			"new":    new,
+		})
+	}
+	return res
+}
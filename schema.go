@@ -0,0 +1,261 @@
+package feta
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+var schemaTypes = map[string]bool{
+	"bool":     true,
+	"number":   true,
+	"string":   true,
+	"dict":     true,
+	"list":     true,
+	"none":     true,
+	"object":   true,
+	"date":     true,
+	"duration": true,
+	"any":      true,
+}
+
+// getSchema returns the schema governing the object, which is the one found
+// in the nearest directory above it.
+func (o *object) getSchema() (fDict, error) {
+	if o.parent == nil {
+		return nil, nil
+	}
+	return o.parent.childSchema()
+}
+
+func (o *object) childSchema() (fDict, error) {
+	if o.isSchemaSet {
+		return o.schema, nil
+	}
+	path := o.sysPath() + ".feta/schema"
+	isSchema, err := fileExists(path)
+	if err != nil {
+		return nil, err
+	}
+	var schema fDict
+	if isSchema {
+		schema, err = readSchema(path)
+		if err != nil {
+			return nil, err
+		}
+	} else if o.parent != nil {
+		schema, err = o.parent.childSchema()
+		if err != nil {
+			return nil, err
+		}
+	}
+	o.schema = schema
+	o.isSchemaSet = true
+	return schema, nil
+}
+
+func readSchema(path string) (fDict, error) {
+	js, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Couldn't read schema file: %v", err)
+	}
+	schema, _, err := parseSidecar(path, js)
+	if err != nil {
+		return nil, fmt.Errorf("Couldn't parse schema file '%s': %v", path, err)
+	}
+	attrs, isDict := schema["attributes"].(fDict)
+	if !isDict {
+		attrs = fDict{}
+		schema["attributes"] = attrs
+	}
+	templates, isList := schema["templates"].(fList)
+	if !isList {
+		templates = fList{}
+		schema["templates"] = templates
+	}
+	for _, tmpl_ := range templates {
+		tmpl, isDict := tmpl_.(fDict)
+		if !isDict {
+			return nil, fmt.Errorf("Template in '%s' isn't a dict", path)
+		}
+		if _, isStr := tmpl["match"].(fString); !isStr {
+			return nil, fmt.Errorf("Template in '%s' has no match pattern", path)
+		}
+		if _, isDict := tmpl["values"].(fDict); !isDict {
+			return nil, fmt.Errorf("Template in '%s' has no values dict", path)
+		}
+	}
+	for name, spec_ := range attrs {
+		spec, isDict := spec_.(fDict)
+		if !isDict {
+			return nil, fmt.Errorf("Schema of attribute '%s' in '%s' isn't a dict", name, path)
+		}
+		for _, t := range schemaTypeList(spec) {
+			if !schemaTypes[t] {
+				return nil, fmt.Errorf("Unknown type '%s' for attribute '%s' in '%s'", t, name, path)
+			}
+		}
+	}
+	return schema, nil
+}
+
+// defaults returns the values the schema of the object provides for missing
+// attributes. Matching templates override each other in order, and all of
+// them override the attribute defaults.
+func (o *object) defaults() (fDict, error) {
+	schema, err := o.getSchema()
+	if err != nil || schema == nil {
+		return fDict{}, err
+	}
+	defaults := fDict{}
+	for name, spec := range schema["attributes"].(fDict) {
+		if value, exists := spec.(fDict)["default"]; exists {
+			defaults[name] = deepCopy(value)
+		}
+	}
+	for _, tmpl_ := range schema["templates"].(fList) {
+		tmpl := tmpl_.(fDict)
+		matched, err := filepath.Match(string(tmpl["match"].(fString)), o.dirEntry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("Invalid template pattern: %v", err)
+		}
+		if matched {
+			for name, value := range tmpl["values"].(fDict) {
+				defaults[name] = deepCopy(value)
+			}
+		}
+	}
+	return defaults, nil
+}
+
+// schemaTypeList returns the types allowed by an attribute spec, which can be
+// given as a single type name or a list of them.
+func schemaTypeList(spec fDict) []string {
+	switch t := spec["type"].(type) {
+	case fString:
+		return []string{string(t)}
+	case fList:
+		types := []string{}
+		for _, elm := range t {
+			if s, isStr := elm.(fString); isStr {
+				types = append(types, string(s))
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+func isSet(dict fDict, key string) bool {
+	value, exists := dict[key]
+	return exists && bool(boolVal(value))
+}
+
+// validate checks the raw metadata of an object against its schema and
+// returns the violations found.
+func (o *object) validate(meta fDict) ([]string, error) {
+	schema, err := o.getSchema()
+	if err != nil || schema == nil {
+		return nil, err
+	}
+	attrs := schema["attributes"].(fDict)
+	defaults, err := o.defaults()
+	if err != nil {
+		return nil, err
+	}
+	evaluated := deepCopy(meta).(fDict)
+	insertDefaults(evaluated, defaults)
+	insertProcedurals(evaluated)
+	ctx := &context{obj: o, meta: evaluated, vars: cliVars()}
+	violations := []string{}
+	for _, name := range sortedKeys(attrs) {
+		spec := attrs[name].(fDict)
+		raw, exists := meta[name]
+		if !exists {
+			_, hasDefault := defaults[name]
+			if isSet(spec, "required") && !hasDefault {
+				violations = append(violations, "Missing required attribute '"+name+"'")
+			}
+			continue
+		}
+		value := evalStored(ctx, raw)
+		if fErr, ok := value.(fError); ok {
+			violations = append(violations, "Attribute '"+name+"' has error: "+fErr.msg)
+			continue
+		}
+		if types := schemaTypeList(spec); len(types) != 0 {
+			valid := false
+			for _, t := range types {
+				if t == "any" || t == typeName(value) {
+					valid = true
+				}
+			}
+			if !valid {
+				violations = append(violations, fmt.Sprintf("Attribute '%s' should be %v, not %s", name, types, typeName(value)))
+			}
+		}
+		if allowed, isList := spec["allowed"].(fList); isList {
+			if !bool(contains(allowed, value).(fBool)) {
+				violations = append(violations, fmt.Sprintf("Attribute '%s' has value %s not in %s", name, inline(value), inline(allowed)))
+			}
+		}
+	}
+	if isSet(schema, "strict") {
+		for _, name := range sortedKeys(meta) {
+			if _, declared := attrs[name]; !declared && name != idKey && name != tagsKey {
+				violations = append(violations, "Unknown attribute '"+name+"'")
+			}
+		}
+	}
+	return violations, nil
+}
+
+// checkNewViolations returns an error if meta violates the schema of the
+// object in ways not listed in before.
+func (o *object) checkNewViolations(meta fDict, before []string) error {
+	after, err := o.validate(meta)
+	if err != nil {
+		return fmt.Errorf("%v at %s", err, o.fetaPath())
+	}
+	old := map[string]bool{}
+	for _, v := range before {
+		old[v] = true
+	}
+	added := []string{}
+	for _, v := range after {
+		if !old[v] {
+			added = append(added, v)
+		}
+	}
+	if len(added) != 0 {
+		return fmt.Errorf("Invalid metadata for %s: %s", o.fetaPath(), strings.Join(added, "; "))
+	}
+	return nil
+}
+
+func Validate(query string, workDir string) ([]byte, bool, error) {
+	objs, err := selectObjects(query, workDir)
+	if err != nil {
+		return nil, false, err
+	}
+	res := fList{}
+	for _, o := range objs {
+		meta, err := o.readMeta()
+		if err != nil {
+			return nil, false, fmt.Errorf("%v at %s", err, o.fetaPath())
+		}
+		violations, err := o.validate(meta)
+		if err != nil {
+			return nil, false, fmt.Errorf("%v at %s", err, o.fetaPath())
+		}
+		if len(violations) != 0 {
+			list := make(fList, len(violations))
+			for i, v := range violations {
+				list[i] = fString(v)
+			}
+			res = append(res, fDict{"Obj": o, "Violations": list})
+		}
+	}
+	return marshal(res, !Flags.UglyJSON), len(res) == 0, nil
+}
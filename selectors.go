@@ -7,9 +7,62 @@ import (
 )
 
 type context struct {
-	obj  *object
-	raw  bool
-	meta fExpr
+	obj        *object
+	raw        bool
+	meta       fExpr
+	scope      *scope
+	vars       map[string]fExpr
+	evaluating map[evalKey]bool
+}
+
+// evalKey identifies a value stored in the metadata of an object while it is
+// being evaluated.
+type evalKey struct {
+	obj   *object
+	value uintptr
+}
+
+// enter returns a context for resolving names in the metadata of a referenced
+// object.
+func (ctx *context) enter(o *object) (*context, fExpr) {
+	meta, err := o.getMeta()
+	if err != nil {
+		return nil, fError{err.Error() + " at " + o.fetaPath()}
+	}
+	return ctx.at(o, meta), nil
+}
+
+// at returns a context for o with the given metadata, which shares the scope,
+// the variables and the values under evaluation with ctx.
+func (ctx *context) at(o *object, meta fExpr) *context {
+	if ctx.evaluating == nil {
+		ctx.evaluating = map[evalKey]bool{}
+	}
+	return &context{obj: o, meta: meta, scope: ctx.scope, vars: ctx.vars, evaluating: ctx.evaluating}
+}
+
+// A scope holds the names bound by let and lambdas, which shadow the
+// attributes of the same name. Variables given from outside, like those on
+// the command line, are kept apart in the context and only reached with '$'.
+type scope struct {
+	vars   map[string]fExpr
+	parent *scope
+}
+
+func (sc *scope) lookup(name string) (fExpr, bool) {
+	for ; sc != nil; sc = sc.parent {
+		if value, exists := sc.vars[name]; exists {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+// withScope returns a copy of the context with a new scope binding vars.
+func (ctx *context) withScope(vars map[string]fExpr) *context {
+	c := *ctx
+	c.scope = &scope{vars: vars, parent: ctx.scope}
+	return &c
 }
 
 type selector interface {
@@ -52,7 +105,7 @@ func (sel *dirSel) sel(ctx *context) fList {
 				return fList{fError{err.Error() + " at " + ctx.obj.fetaPath()}}
 			}
 			for _, ch := range chs {
-				chRes := sel.next.sel(&context{obj: ch, meta: ctx.meta})
+				chRes := sel.next.sel(ctx.at(ch, ctx.meta))
 				res = append(res, chRes...)
 			}
 			return res
@@ -90,8 +143,8 @@ func (sel *rootSel) setNext(next selector) {
 	sel.next = next
 }
 
-func (sel *rootSel) sel(_ *context) fList {
-	return sel.next.sel(&context{obj: site})
+func (sel *rootSel) sel(ctx *context) fList {
+	return sel.next.sel(ctx.at(site, nil))
 }
 
 type relSel struct {
@@ -112,11 +165,31 @@ func (sel *relSel) sel(ctx *context) fList {
 		}
 	}
 	if sel.next != nil {
-		return sel.next.sel(&context{obj: anch, meta: ctx.meta})
+		return sel.next.sel(ctx.at(anch, ctx.meta))
 	}
 	return fList{anch}
 }
 
+type idSel struct {
+	id   string
+	next selector
+}
+
+func (sel *idSel) setNext(next selector) {
+	sel.next = next
+}
+
+func (sel *idSel) sel(ctx *context) fList {
+	o, err := findByID(sel.id)
+	if err != nil {
+		return fList{fError{err.Error()}}
+	}
+	if sel.next != nil {
+		return sel.next.sel(ctx.at(o, nil))
+	}
+	return fList{o}
+}
+
 type recurseSel struct {
 	next selector
 }
@@ -133,13 +206,13 @@ func walk(ctx *context, next selector) fList {
 	res := fList{}
 	for _, ch := range chs {
 		if next != nil {
-			chRes := next.sel(&context{obj: ch})
+			chRes := next.sel(ctx.at(ch, nil))
 			res = append(res, chRes...)
 		} else {
 			res = append(res, ch)
 		}
 		if ch.dirEntry.IsDir() {
-			chRes := walk(&context{obj: ch}, next)
+			chRes := walk(ctx.at(ch, nil), next)
 			res = append(res, chRes...)
 		}
 	}
@@ -164,17 +237,10 @@ func (sel *tailSel) sel(ctx *context) fList {
 	}
 	res := fDict{"Obj": ctx.obj}
 	if sel.expr == nil {
-		for k, v := range procedurals {
-			pr := v.eval(ctx)
-			if fErr, ok := pr.(fError); ok {
-				return fList{fErr}
-			}
-			ns[k] = pr
-		}
-		res["Value"] = ns.eval(ctx)
+		res["Value"] = ns.eval(ctx.at(ctx.obj, ns))
 		return fList{res}
 	}
-	meta := sel.expr.eval(&context{obj: ctx.obj, meta: ns})
+	meta := sel.expr.eval(ctx.at(ctx.obj, ns))
 	if fErr, ok := meta.(fError); ok {
 		return fList{fErr}
 	}
@@ -196,7 +262,7 @@ func (sel *filterSel) sel(ctx *context) fList {
 	if err != nil {
 		return fList{fError{err.Error() + " at " + ctx.obj.fetaPath()}}
 	}
-	value := sel.expr.eval(&context{obj: ctx.obj, meta: ns})
+	value := sel.expr.eval(ctx.at(ctx.obj, ns))
 	if fErr, ok := value.(fError); ok {
 		return fList{fErr}
 	}
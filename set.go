@@ -0,0 +1,204 @@
+package feta
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var identRex = regexp.MustCompile(`^[\pL\pNd_]+$`)
+
+// pathKey returns the form of a dict key in attribute paths, which is the
+// key itself for identifiers and the quoted key in brackets otherwise.
+func pathKey(key string) string {
+	if identRex.MatchString(key) {
+		return key
+	}
+	return "[" + strconv.Quote(key) + "]"
+}
+
+// childPath appends a name in the form of pathKey, or a list index in
+// brackets, to an attribute path.
+func childPath(path string, name string) string {
+	if path == "" || strings.HasPrefix(name, "[") {
+		return path + name
+	}
+	return path + "." + name
+}
+
+func joinPath(path string, key string) string {
+	return childPath(path, pathKey(key))
+}
+
+// splitPath returns the keys of an attribute path, where keys are separated
+// by dots or given as quoted strings in brackets, like a.b["c.d"].
+func splitPath(path string) ([]string, error) {
+	names := []string{}
+	rest := path
+	for {
+		if strings.HasPrefix(rest, `["`) {
+			end := 2
+			for end < len(rest) && rest[end] != '"' {
+				if rest[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end+1 >= len(rest) || rest[end+1] != ']' {
+				return nil, fmt.Errorf("Invalid attribute path '%s'", path)
+			}
+			name, err := strconv.Unquote(rest[1 : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("Invalid attribute path '%s'", path)
+			}
+			names = append(names, name)
+			rest = rest[end+2:]
+		} else {
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("Invalid attribute path '%s'", path)
+			}
+			names = append(names, rest[:end])
+			rest = rest[end:]
+		}
+		if rest == "" {
+			return names, nil
+		}
+		if strings.HasPrefix(rest, ".") {
+			rest = rest[1:]
+		} else if !strings.HasPrefix(rest, "[") {
+			return nil, fmt.Errorf("Invalid attribute path '%s'", path)
+		}
+	}
+}
+
+// setPath stores value at the attribute path in meta, creating the missing
+// dicts on the way.
+func setPath(meta fDict, path string, value fExpr) error {
+	names, err := splitPath(path)
+	if err != nil {
+		return err
+	}
+	for _, name := range names[:len(names)-1] {
+		next, exists := meta[name]
+		if !exists {
+			next = fDict{}
+			meta[name] = next
+		}
+		dict, isDict := next.(fDict)
+		if !isDict {
+			return fmt.Errorf("Attribute '%s' of '%s' isn't a dict", name, path)
+		}
+		meta = dict
+	}
+	meta[names[len(names)-1]] = value
+	return nil
+}
+
+// getPath returns the value at the attribute path in meta.
+func getPath(meta fDict, path string) (fExpr, bool) {
+	names, err := splitPath(path)
+	if err != nil {
+		return nil, false
+	}
+	for _, name := range names[:len(names)-1] {
+		dict, isDict := meta[name].(fDict)
+		if !isDict {
+			return nil, false
+		}
+		meta = dict
+	}
+	value, exists := meta[names[len(names)-1]]
+	return value, exists
+}
+
+// unsetPath removes the value at the attribute path from meta.
+func unsetPath(meta fDict, path string) {
+	names, err := splitPath(path)
+	if err != nil {
+		return
+	}
+	for _, name := range names[:len(names)-1] {
+		dict, isDict := meta[name].(fDict)
+		if !isDict {
+			return
+		}
+		meta = dict
+	}
+	delete(meta, names[len(names)-1])
+}
+
+// prepare applies change to the raw sidecar dicts of the objects, and returns
+// the changed dicts with the records of the changes. It fails if any of the
+// changed sidecars would get new schema violations.
+func prepare(objs []*object, change func(o *object, meta fDict) error) ([]fDict, []historyRecord, error) {
+	metas := make([]fDict, len(objs))
+	records := []historyRecord{}
+	for i, o := range objs {
+		meta, err := o.readMeta()
+		if err != nil {
+			return nil, nil, fmt.Errorf("%v at %s", err, o.fetaPath())
+		}
+		before, err := o.validate(meta)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%v at %s", err, o.fetaPath())
+		}
+		old := deepCopy(meta).(fDict)
+		if err := change(o, meta); err != nil {
+			return nil, nil, fmt.Errorf("%v at %s", err, o.fetaPath())
+		}
+		if err := o.checkNewViolations(meta, before); err != nil {
+			return nil, nil, err
+		}
+		metas[i] = meta
+		records = append(records, diffMeta(o.fetaPath(), "", old, meta)...)
+	}
+	return metas, records, nil
+}
+
+// modify applies change to the raw sidecar dicts of the objects and writes
+// them back under the lock of the site. Nothing is written if any of the
+// changed sidecars would get new schema violations.
+func modify(objs []*object, change func(o *object, meta fDict) error) (fList, error) {
+	unlock, err := lockSite()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	metas, records, err := prepare(objs, change)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeBatch(objs, metas, records); err != nil {
+		return nil, err
+	}
+	res := fList{}
+	for _, o := range objs {
+		res = append(res, o)
+	}
+	return res, nil
+}
+
+// Set stores the unevaluated value expression at the attribute path in the
+// sidecars of the selected objects.
+func Set(query string, path string, value string, workDir string) ([]byte, error) {
+	objs, err := selectObjects(query, workDir)
+	if err != nil {
+		return nil, err
+	}
+	expr, err := Parse(value, []byte(value), Entrypoint("Expression"))
+	if err != nil {
+		return nil, fmt.Errorf("Couldn't parse value '%s': %v", value, err)
+	}
+	res, err := modify(objs, func(o *object, meta fDict) error {
+		return setPath(meta, path, deepCopy(expr.(fExpr)))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return marshal(res, !Flags.UglyJSON), nil
+}
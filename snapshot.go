@@ -0,0 +1,284 @@
+package feta
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// A snapshotEntry holds the raw metadata of an object, and optionally its
+// stat info, at the time of a snapshot.
+type snapshotEntry struct {
+	Meta    *string `json:",omitempty"`
+	IsDir   bool    `json:",omitempty"`
+	Size    *int64  `json:",omitempty"`
+	ModTime string  `json:",omitempty"`
+}
+
+// A snapshot holds the entries of the objects of the site by feta path, and
+// whether they have stat info.
+type snapshot struct {
+	Time    string
+	Stat    bool `json:",omitempty"`
+	Objects map[string]snapshotEntry
+}
+
+func snapshotDir() string {
+	return site.sysPath() + ".feta/snapshots"
+}
+
+// takeSnapshot captures the metadata of every object of the site, and the
+// stat info too if withStat is set. Objects without metadata are only
+// included with stat info.
+func takeSnapshot(withStat bool) (*snapshot, error) {
+	st, err := getStorage()
+	if err != nil {
+		return nil, err
+	}
+	objs, err := siteObjects()
+	if err != nil {
+		return nil, err
+	}
+	snap := &snapshot{
+		Time:    time.Now().UTC().Format(time.RFC3339),
+		Stat:    withStat,
+		Objects: map[string]snapshotEntry{},
+	}
+	for _, o := range objs {
+		entry := snapshotEntry{IsDir: o.dirEntry.IsDir()}
+		data, err := st.read(o)
+		if err != nil {
+			return nil, fmt.Errorf("%v at %s", err, o.fetaPath())
+		}
+		if data != nil {
+			s := string(data)
+			entry.Meta = &s
+		}
+		if withStat {
+			fi, err := o.dirEntry.Info()
+			if err != nil {
+				return nil, fmt.Errorf("Couldn't stat %s: %v", o.fetaPath(), err)
+			}
+			size := fi.Size()
+			entry.Size = &size
+			entry.ModTime = fi.ModTime().UTC().Format(time.RFC3339Nano)
+		} else if data == nil {
+			continue
+		}
+		snap.Objects[o.fetaPath()] = entry
+	}
+	return snap, nil
+}
+
+func checkSnapshotName(name string) error {
+	if name == "" || name == "live" || strings.ContainsAny(name, "/\\") || strings.HasPrefix(name, ".") {
+		return fmt.Errorf("Invalid snapshot name '%s'", name)
+	}
+	return nil
+}
+
+func CreateSnapshot(name string, withStat bool) ([]byte, error) {
+	if err := checkSnapshotName(name); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(snapshotDir(), name)
+	if exists, err := fileExists(path); err != nil {
+		return nil, err
+	} else if exists {
+		return nil, fmt.Errorf("Snapshot '%s' already exists", name)
+	}
+	snap, err := takeSnapshot(withStat)
+	if err != nil {
+		return nil, err
+	}
+	js, err := json.Marshal(snap)
+	if err != nil {
+		return nil, fmt.Errorf("Couldn't encode snapshot: %v", err)
+	}
+	if err := os.MkdirAll(snapshotDir(), 0755); err != nil {
+		return nil, fmt.Errorf("Couldn't create snapshot dir: %v", err)
+	}
+	if err := writeFileAtomic(path, js); err != nil {
+		return nil, fmt.Errorf("Couldn't write snapshot: %v", err)
+	}
+	res := fDict{"Name": fString(name), "Objects": fNumber(len(snap.Objects))}
+	return marshal(res, !Flags.UglyJSON), nil
+}
+
+// Snapshots lists the snapshots of the site with their creation times.
+func Snapshots() ([]byte, error) {
+	des, err := os.ReadDir(snapshotDir())
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("Couldn't read snapshot dir: %v", err)
+	}
+	res := fDict{}
+	for _, de := range des {
+		if checkSnapshotName(de.Name()) != nil {
+			continue
+		}
+		snap, err := loadSnapshot(de.Name(), false)
+		if err != nil {
+			return nil, err
+		}
+		res[de.Name()] = fString(snap.Time)
+	}
+	return marshal(res, !Flags.UglyJSON), nil
+}
+
+// loadSnapshot reads the named snapshot, or takes one of the current state
+// of the site for the name 'live', with stat info if withStat is set.
+func loadSnapshot(name string, withStat bool) (*snapshot, error) {
+	if name == "live" {
+		return takeSnapshot(withStat)
+	}
+	if err := checkSnapshotName(name); err != nil {
+		return nil, err
+	}
+	js, err := ioutil.ReadFile(filepath.Join(snapshotDir(), name))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("Unknown snapshot '%s'", name)
+		}
+		return nil, fmt.Errorf("Couldn't read snapshot: %v", err)
+	}
+	snap := &snapshot{}
+	if err := json.Unmarshal(js, snap); err != nil {
+		return nil, fmt.Errorf("Couldn't decode snapshot '%s': %v", name, err)
+	}
+	for _, entry := range snap.Objects {
+		if entry.Size != nil {
+			snap.Stat = true
+			break
+		}
+	}
+	return snap, nil
+}
+
+func (entry snapshotEntry) meta(path string) (fDict, error) {
+	if entry.Meta == nil {
+		return fDict{}, nil
+	}
+	dict, _, err := parseSidecar(path, []byte(*entry.Meta))
+	if err != nil {
+		return nil, fmt.Errorf("Couldn't parse metadata of %s: %v", path, err)
+	}
+	return dict, nil
+}
+
+// statRecords compares the stat info of two entries when both have it.
+func statRecords(path string, a snapshotEntry, b snapshotEntry) []historyRecord {
+	if a.Size == nil || b.Size == nil {
+		return nil
+	}
+	records := []historyRecord{}
+	if *a.Size != *b.Size {
+		old, new := fmt.Sprint(*a.Size), fmt.Sprint(*b.Size)
+		records = append(records, historyRecord{Obj: path, Path: "stat.size", Old: &old, New: &new})
+	}
+	if a.ModTime != b.ModTime {
+		old, new := inline(fString(a.ModTime)), inline(fString(b.ModTime))
+		records = append(records, historyRecord{Obj: path, Path: "stat.mtime", Old: &old, New: &new})
+	}
+	return records
+}
+
+// loadSnapshots loads the snapshots to compare, taking a 'live' one with stat
+// info only if the stored one has it too.
+func loadSnapshots(nameA string, nameB string) (*snapshot, *snapshot, error) {
+	names := []string{nameA, nameB}
+	snaps := make([]*snapshot, 2)
+	withStat := false
+	for i, name := range names {
+		if name == "live" {
+			continue
+		}
+		snap, err := loadSnapshot(name, false)
+		if err != nil {
+			return nil, nil, err
+		}
+		snaps[i] = snap
+		withStat = withStat || snap.Stat
+	}
+	for i, name := range names {
+		if snaps[i] != nil {
+			continue
+		}
+		snap, err := loadSnapshot(name, withStat)
+		if err != nil {
+			return nil, nil, err
+		}
+		snaps[i] = snap
+	}
+	return snaps[0], snaps[1], nil
+}
+
+// Diff reports the objects and attributes added, removed or modified between
+// two snapshots, where 'live' stands for the current state of the site. A
+// query limits the report to the objects it selects in the current tree.
+// Objects are only reported with changes in their metadata, or in their stat
+// info when both snapshots have it.
+func Diff(nameA string, nameB string, query string, workDir string) ([]byte, error) {
+	a, b, err := loadSnapshots(nameA, nameB)
+	if err != nil {
+		return nil, err
+	}
+	var selected map[string]bool
+	if query != "" {
+		objs, err := selectObjects(query, workDir)
+		if err != nil {
+			return nil, err
+		}
+		selected = map[string]bool{}
+		for _, o := range objs {
+			selected[o.fetaPath()] = true
+		}
+	}
+	paths := fDict{}
+	for p := range a.Objects {
+		paths[p] = fNone{}
+	}
+	for p := range b.Objects {
+		paths[p] = fNone{}
+	}
+	res := fList{}
+	for _, path := range sortedKeys(paths) {
+		if selected != nil && !selected[path] {
+			continue
+		}
+		entryA, inA := a.Objects[path]
+		entryB, inB := b.Objects[path]
+		metaA, err := entryA.meta(path)
+		if err != nil {
+			return nil, err
+		}
+		metaB, err := entryB.meta(path)
+		if err != nil {
+			return nil, err
+		}
+		records := append(diffMeta(path, "", metaA, metaB), statRecords(path, entryA, entryB)...)
+		if len(records) == 0 {
+			continue
+		}
+		change := "modified"
+		if !inA {
+			change = "added"
+		} else if !inB {
+			change = "removed"
+		}
+		attrs := fList{}
+		for _, rec := range records {
+			old, new, err := rec.values()
+			if err != nil {
+				return nil, err
+			}
+			attrs = append(attrs, fDict{"Path": fString(rec.Path), "Old": old, "New": new})
+		}
+		res = append(res, fDict{"Obj": fString(path), "Change": fString(change), "Attributes": attrs})
+	}
+	return marshal(res, !Flags.UglyJSON), nil
+}
@@ -0,0 +1,213 @@
+package feta
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// A storage keeps the serialized metadata of objects. Reading an object
+// without metadata returns nil.
+type storage interface {
+	read(o *object) ([]byte, error)
+	write(o *object, data []byte) error
+	remove(o *object) error
+	location(o *object) string
+}
+
+var storages = map[string]func() storage{
+	"sidecar": func() storage { return sidecarStorage{} },
+	"xattr":   func() storage { return xattrStorage{} },
+	"db":      func() storage { return &dbStorage{} },
+}
+
+// store is the storage of the site, as set in the site config.
+var (
+	store     storage
+	storeName string
+)
+
+// closeStorage releases the resources held by the storage of the site.
+func closeStorage() error {
+	if c, isCloser := store.(io.Closer); isCloser {
+		if err := c.Close(); err != nil {
+			return err
+		}
+	}
+	store = nil
+	storeName = ""
+	return nil
+}
+
+func siteConfigPath() string {
+	return site.sysPath() + ".feta/config"
+}
+
+func readConfig() (fDict, error) {
+	path := siteConfigPath()
+	js, err := ioutil.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fDict{}, nil
+		}
+		return nil, fmt.Errorf("Couldn't read site config: %v", err)
+	}
+	config, _, err := parseSidecar(path, js)
+	if err != nil {
+		return nil, fmt.Errorf("Couldn't parse site config '%s': %v", path, err)
+	}
+	return config, nil
+}
+
+func writeConfig(config fDict) error {
+	path := siteConfigPath()
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return fmt.Errorf("Couldn't create site config dir: %v", err)
+	}
+	err = writeFileAtomic(path, marshal(config, true))
+	if err != nil {
+		return fmt.Errorf("Couldn't write site config: %v", err)
+	}
+	return nil
+}
+
+// getStorage returns the storage selected by the 'storage' setting of the
+// site config, which defaults to sidecar files.
+func getStorage() (storage, error) {
+	if store != nil {
+		return store, nil
+	}
+	config, err := readConfig()
+	if err != nil {
+		return nil, err
+	}
+	name := "sidecar"
+	if s, isStr := config["storage"].(fString); isStr {
+		name = string(s)
+	}
+	newStorage, exists := storages[name]
+	if !exists {
+		return nil, fmt.Errorf("Unknown storage '%s' in site config", name)
+	}
+	store = newStorage()
+	storeName = name
+	return store, nil
+}
+
+// Migrate moves the metadata of every object of the site to the named
+// storage and makes it the storage of the site.
+func Migrate(to string) ([]byte, error) {
+	newStorage, exists := storages[to]
+	if !exists {
+		return nil, fmt.Errorf("Unknown storage '%s'", to)
+	}
+	from, err := getStorage()
+	if err != nil {
+		return nil, err
+	}
+	if storeName == to {
+		return nil, fmt.Errorf("Site already uses %s storage", to)
+	}
+	target := newStorage()
+	unlock, err := lockSite()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	objs, err := siteObjects()
+	if err != nil {
+		return nil, err
+	}
+	moved := []*object{}
+	for _, o := range objs {
+		data, err := from.read(o)
+		if err != nil {
+			return nil, fmt.Errorf("%v at %s", err, o.fetaPath())
+		}
+		if data == nil {
+			continue
+		}
+		if err := target.write(o, data); err != nil {
+			return nil, fmt.Errorf("%v at %s", err, o.fetaPath())
+		}
+		moved = append(moved, o)
+	}
+	config, err := readConfig()
+	if err != nil {
+		return nil, err
+	}
+	config["storage"] = fString(to)
+	if err := writeConfig(config); err != nil {
+		return nil, err
+	}
+	res := fList{}
+	for _, o := range moved {
+		if err := from.remove(o); err != nil {
+			return nil, fmt.Errorf("%v at %s", err, o.fetaPath())
+		}
+		o.meta = nil
+		res = append(res, o)
+	}
+	if err := closeStorage(); err != nil {
+		return nil, err
+	}
+	store = target
+	storeName = to
+	return marshal(res, !Flags.UglyJSON), nil
+}
+
+// sidecarStorage keeps metadata in files under the .feta dir next to the
+// objects.
+type sidecarStorage struct{}
+
+func (sidecarStorage) read(o *object) ([]byte, error) {
+	data, err := ioutil.ReadFile(o.metaPath())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("Couldn't read meta file: %v", err)
+	}
+	return data, nil
+}
+
+func (sidecarStorage) write(o *object, data []byte) error {
+	path := o.metaPath()
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return fmt.Errorf("Couldn't create meta dir: %v", err)
+	}
+	err = writeFileAtomic(path, data)
+	if err != nil {
+		return fmt.Errorf("Couldn't write meta file: %v", err)
+	}
+	return nil
+}
+
+func (sidecarStorage) remove(o *object) error {
+	path := o.metaPath()
+	err := os.Remove(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("Couldn't remove meta file: %v", err)
+	}
+	// The .feta dir is only removed when nothing else is left in it.
+	os.Remove(filepath.Dir(path))
+	return nil
+}
+
+func (sidecarStorage) location(o *object) string {
+	return o.metaPath()
+}
+
+// xattrStorage keeps metadata in an extended attribute of the objects.
+type xattrStorage struct{}
+
+const xattrName = "user.feta"
+
+func (xattrStorage) location(o *object) string {
+	return o.sysPath() + " (" + xattrName + ")"
+}
@@ -0,0 +1,119 @@
+package feta
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var metaBucket = []byte("meta")
+
+// dbStorage keeps the metadata of the whole site in a single database file
+// under the .feta dir of the site, keyed by the feta paths of the objects.
+type dbStorage struct {
+	db       *bolt.DB
+	writable bool
+}
+
+func dbPath() string {
+	return site.sysPath() + ".feta/meta.db"
+}
+
+// open opens the database, read-only unless write is set, so that readers
+// only share its lock. A read-only database is reopened for writing, and a
+// missing one is nil for reading.
+func (st *dbStorage) open(write bool) (*bolt.DB, error) {
+	if st.db != nil && (st.writable || !write) {
+		return st.db, nil
+	}
+	if err := st.Close(); err != nil {
+		return nil, fmt.Errorf("Couldn't close meta database: %v", err)
+	}
+	if write {
+		if err := os.MkdirAll(filepath.Dir(dbPath()), 0755); err != nil {
+			return nil, fmt.Errorf("Couldn't create meta dir: %v", err)
+		}
+	} else if exists, err := fileExists(dbPath()); err != nil || !exists {
+		return nil, err
+	}
+	db, err := bolt.Open(dbPath(), 0644, &bolt.Options{Timeout: 10 * time.Second, ReadOnly: !write})
+	if err != nil {
+		return nil, fmt.Errorf("Couldn't open meta database: %v", err)
+	}
+	st.db, st.writable = db, write
+	return db, nil
+}
+
+func (st *dbStorage) read(o *object) ([]byte, error) {
+	db, err := st.open(false)
+	if err != nil || db == nil {
+		return nil, err
+	}
+	var data []byte
+	err = db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(metaBucket)
+		if b == nil {
+			return nil
+		}
+		if v := b.Get([]byte(o.fetaPath())); v != nil {
+			data = append([]byte{}, v...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Couldn't read meta database: %v", err)
+	}
+	return data, nil
+}
+
+func (st *dbStorage) write(o *object, data []byte) error {
+	db, err := st.open(true)
+	if err != nil {
+		return err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(metaBucket)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(o.fetaPath()), data)
+	})
+	if err != nil {
+		return fmt.Errorf("Couldn't write meta database: %v", err)
+	}
+	return nil
+}
+
+func (st *dbStorage) remove(o *object) error {
+	db, err := st.open(true)
+	if err != nil {
+		return err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(metaBucket)
+		if b == nil {
+			return nil
+		}
+		return b.Delete([]byte(o.fetaPath()))
+	})
+	if err != nil {
+		return fmt.Errorf("Couldn't write meta database: %v", err)
+	}
+	return nil
+}
+
+func (st *dbStorage) location(o *object) string {
+	return dbPath() + ":" + o.fetaPath()
+}
+
+func (st *dbStorage) Close() error {
+	if st.db == nil {
+		return nil
+	}
+	err := st.db.Close()
+	st.db = nil
+	return err
+}
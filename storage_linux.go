@@ -0,0 +1,41 @@
+//go:build linux
+
+package feta
+
+import (
+	"fmt"
+	"syscall"
+)
+
+func (xattrStorage) read(o *object) ([]byte, error) {
+	path := o.sysPath()
+	size, err := syscall.Getxattr(path, xattrName, nil)
+	if err == syscall.ENODATA {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Couldn't read xattr: %v", err)
+	}
+	data := make([]byte, size)
+	size, err = syscall.Getxattr(path, xattrName, data)
+	if err != nil {
+		return nil, fmt.Errorf("Couldn't read xattr: %v", err)
+	}
+	return data[:size], nil
+}
+
+func (xattrStorage) write(o *object, data []byte) error {
+	err := syscall.Setxattr(o.sysPath(), xattrName, data, 0)
+	if err != nil {
+		return fmt.Errorf("Couldn't write xattr: %v", err)
+	}
+	return nil
+}
+
+func (xattrStorage) remove(o *object) error {
+	err := syscall.Removexattr(o.sysPath(), xattrName)
+	if err != nil && err != syscall.ENODATA {
+		return fmt.Errorf("Couldn't remove xattr: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+//go:build !linux
+
+package feta
+
+import (
+	"errors"
+)
+
+var errNoXattr = errors.New("Xattr storage is only supported on Linux")
+
+func (xattrStorage) read(o *object) ([]byte, error) {
+	return nil, errNoXattr
+}
+
+func (xattrStorage) write(o *object, data []byte) error {
+	return errNoXattr
+}
+
+func (xattrStorage) remove(o *object) error {
+	return errNoXattr
+}
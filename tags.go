@@ -0,0 +1,196 @@
+package feta
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/maruel/natural"
+)
+
+// tagsKey is the attribute holding the sorted list of tags of an object.
+const tagsKey = "tags"
+
+var tagRex = regexp.MustCompile(`^[\pL\pNd_-]+$`)
+
+// tags returns the tags of the object, ignoring anything in its tags
+// attribute that isn't a string.
+func (o *object) tags(ctx *context) ([]string, error) {
+	meta, err := o.getMeta()
+	if err != nil {
+		return nil, err
+	}
+	value, exists := meta[tagsKey]
+	if !exists {
+		return nil, nil
+	}
+	list, isList := evalStored(ctx.at(o, meta), value).(fList)
+	if !isList {
+		return nil, nil
+	}
+	tags := []string{}
+	for _, elm := range list {
+		if s, isStr := elm.(fString); isStr {
+			tags = append(tags, string(s))
+		}
+	}
+	return tags, nil
+}
+
+// rawTags returns the tags stored in a raw sidecar dict as a set.
+func rawTags(meta fDict) (map[string]bool, error) {
+	set := map[string]bool{}
+	value, exists := meta[tagsKey]
+	if !exists {
+		return set, nil
+	}
+	list, isList := value.(fList)
+	if !isList {
+		return nil, fmt.Errorf("Attribute '%s' isn't a list", tagsKey)
+	}
+	for _, elm := range list {
+		s, isStr := elm.(fString)
+		if !isStr {
+			return nil, fmt.Errorf("Attribute '%s' holds a non-string value", tagsKey)
+		}
+		set[string(s)] = true
+	}
+	return set, nil
+}
+
+// storeTags writes the tag set sorted into a raw sidecar dict, removing the
+// attribute when no tags are left.
+func storeTags(meta fDict, set map[string]bool) {
+	if len(set) == 0 {
+		delete(meta, tagsKey)
+		return
+	}
+	names := make([]string, 0, len(set))
+	for t := range set {
+		names = append(names, t)
+	}
+	sort.Sort(natural.StringSlice(names))
+	list := make(fList, len(names))
+	for i, t := range names {
+		list[i] = fString(t)
+	}
+	meta[tagsKey] = list
+}
+
+func checkTags(tags []string) error {
+	if len(tags) == 0 {
+		return fmt.Errorf("No tags given")
+	}
+	for _, t := range tags {
+		if !tagRex.MatchString(t) {
+			return fmt.Errorf("Invalid tag '%s'", t)
+		}
+	}
+	return nil
+}
+
+// changeTags adds or removes tags in the sidecars of the selected objects.
+func changeTags(query string, tags []string, add bool, workDir string) ([]byte, error) {
+	if err := checkTags(tags); err != nil {
+		return nil, err
+	}
+	objs, err := selectObjects(query, workDir)
+	if err != nil {
+		return nil, err
+	}
+	res, err := modify(objs, func(o *object, meta fDict) error {
+		set, err := rawTags(meta)
+		if err != nil {
+			return err
+		}
+		for _, t := range tags {
+			if add {
+				set[t] = true
+			} else {
+				delete(set, t)
+			}
+		}
+		storeTags(meta, set)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return marshal(res, !Flags.UglyJSON), nil
+}
+
+func TagAdd(query string, tags []string, workDir string) ([]byte, error) {
+	return changeTags(query, tags, true, workDir)
+}
+
+func TagRemove(query string, tags []string, workDir string) ([]byte, error) {
+	return changeTags(query, tags, false, workDir)
+}
+
+// TagList lists the tags of the selected objects.
+func TagList(query string, workDir string) ([]byte, error) {
+	objs, err := selectObjects(query, workDir)
+	if err != nil {
+		return nil, err
+	}
+	ctx := &context{vars: cliVars()}
+	res := fList{}
+	for _, o := range objs {
+		tags, err := o.tags(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("%v at %s", err, o.fetaPath())
+		}
+		list := make(fList, len(tags))
+		for i, t := range tags {
+			list[i] = fString(t)
+		}
+		res = append(res, fDict{"Obj": o, "Tags": list})
+	}
+	return marshal(res, !Flags.UglyJSON), nil
+}
+
+// Tags counts the objects of the site carrying each tag.
+func Tags() ([]byte, error) {
+	objs, err := siteObjects()
+	if err != nil {
+		return nil, err
+	}
+	ctx := &context{vars: cliVars()}
+	res := fDict{}
+	for _, o := range objs {
+		tags, err := o.tags(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("%v at %s", err, o.fetaPath())
+		}
+		for _, t := range tags {
+			count, _ := res[t].(fNumber)
+			res[t] = count + 1
+		}
+	}
+	return marshal(res, !Flags.UglyJSON), nil
+}
+
+// taggedFn reports whether the context object carries all the given tags.
+func taggedFn(ctx *context, args []fExpr) fExpr {
+	wanted, fErr := stringArgs("tagged", args)
+	if fErr != nil {
+		return fErr
+	}
+	tags, err := ctx.obj.tags(ctx)
+	if err != nil {
+		return fError{err.Error() + " at " + ctx.obj.fetaPath()}
+	}
+	for _, w := range wanted {
+		found := false
+		for _, t := range tags {
+			if t == w {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fBool(false)
+		}
+	}
+	return fBool(true)
+}
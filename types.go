@@ -1,17 +1,30 @@
 package feta
 
+import (
+	"strconv"
+	"time"
+)
+
 type fExpr interface {
 	eval(*context) fExpr
 }
 
 type (
-	fBool   bool
-	fNumber float64
-	fString string
-	fDict   map[string]fExpr
-	fList   []fExpr
-	fError  struct{ msg string }
-	fNone   struct{}
+	fBool     bool
+	fNumber   float64
+	fString   string
+	fDict     map[string]fExpr
+	fList     []fExpr
+	fError    struct{ msg string }
+	fNone     struct{}
+	fRef      string
+	fDate     time.Time
+	fDuration time.Duration
+	fLambda   struct {
+		params []string
+		body   fExpr
+		scope  *scope
+	}
 )
 
 func boolVal(node fExpr) fBool {
@@ -28,12 +41,98 @@ func boolVal(node fExpr) fBool {
 		return len(n) != 0
 	case fList:
 		return len(n) != 0
+	case fDuration:
+		return n != 0
 	case fError:
 		return len(n.msg) != 0
 	}
 	return fBool(true)
 }
 
+func equal(a fExpr, b fExpr) bool {
+	switch l := a.(type) {
+	case fList:
+		r, same := b.(fList)
+		if !same || len(l) != len(r) {
+			return false
+		}
+		for i := range l {
+			if !equal(l[i], r[i]) {
+				return false
+			}
+		}
+		return true
+	case fDict:
+		r, same := b.(fDict)
+		if !same || len(l) != len(r) {
+			return false
+		}
+		for k, v := range l {
+			rv, exists := r[k]
+			if !exists || !equal(v, rv) {
+				return false
+			}
+		}
+		return true
+	case fDate:
+		r, same := b.(fDate)
+		return same && time.Time(l).Equal(time.Time(r))
+	case fError:
+		return false
+	}
+	return a == b
+}
+
+// typeName returns the name of the type of an evaluated value as used in
+// schemas.
+func typeName(value fExpr) string {
+	switch value.(type) {
+	case fBool:
+		return "bool"
+	case fNumber:
+		return "number"
+	case fString:
+		return "string"
+	case fDate:
+		return "date"
+	case fDuration:
+		return "duration"
+	case fDict:
+		return "dict"
+	case fList:
+		return "list"
+	case fNone:
+		return "none"
+	case *object:
+		return "object"
+	case fLambda:
+		return "lambda"
+	case fError:
+		return "error"
+	}
+	return "expression"
+}
+
+// deepCopy copies the dicts and lists of a value, so it can be evaluated
+// without changing the original.
+func deepCopy(value fExpr) fExpr {
+	switch v := value.(type) {
+	case fDict:
+		res := make(fDict, len(v))
+		for k, elm := range v {
+			res[k] = deepCopy(elm)
+		}
+		return res
+	case fList:
+		res := make(fList, len(v))
+		for i, elm := range v {
+			res[i] = deepCopy(elm)
+		}
+		return res
+	}
+	return value
+}
+
 func (value fBool) eval(ctx *context) fExpr {
 	return value
 }
@@ -50,28 +149,76 @@ func (value fString) eval(ctx *context) fExpr {
 	return value
 }
 
+func (value fDate) eval(ctx *context) fExpr {
+	return value
+}
+
+func (value fDuration) eval(ctx *context) fExpr {
+	return value
+}
+
+// Containers evaluate into new containers, so that expressions stored in
+// cached metadata are kept for later evaluations and raw access.
 func (value fDict) eval(ctx *context) fExpr {
+	res := make(fDict, len(value))
 	for k, elm := range value {
-		res := elm.eval(ctx)
-		if fErr, ok := res.(fError); ok {
+		// The history only comes up when it is asked for by name, as it
+		// grows with every change.
+		if _, isHistory := elm.(*historyProc); isHistory {
+			continue
+		}
+		v := evalStored(ctx, elm)
+		if fErr, ok := v.(fError); ok {
 			return fErr
 		}
-		value[k] = res
+		res[k] = v
 	}
-	return value
+	return res
 }
 
 func (value fList) eval(ctx *context) fExpr {
+	res := make(fList, len(value))
 	for i, elm := range value {
-		res := elm.eval(ctx)
-		if fErr, ok := res.(fError); ok {
+		v := evalStored(ctx, elm)
+		if fErr, ok := v.(fError); ok {
 			return fErr
 		}
-		value[i] = res
+		res[i] = v
+	}
+	return res
+}
+
+// A reference resolves to the object at its path. Relative paths start from the
+// object itself for directories and from the containing directory for files.
+func (value fRef) eval(ctx *context) fExpr {
+	base := ctx.obj
+	if !base.dirEntry.IsDir() {
+		base = base.parent
+	}
+	target, err := base.lookup(string(value))
+	if err != nil {
+		return fError{"Dangling reference `" + string(value) + "` from " + ctx.obj.fetaPath()}
 	}
+	return target
+}
+
+func (value fLambda) eval(ctx *context) fExpr {
 	return value
 }
 
+func (value fLambda) call(ctx *context, args ...fExpr) fExpr {
+	if len(args) != len(value.params) {
+		return fError{"Lambda takes exactly " + strconv.Itoa(len(value.params)) + " argument(s)."}
+	}
+	vars := make(map[string]fExpr, len(args))
+	for i, param := range value.params {
+		vars[param] = args[i]
+	}
+	c := *ctx
+	c.scope = value.scope
+	return value.body.eval(c.withScope(vars))
+}
+
 func (value fError) eval(ctx *context) fExpr {
 	return value
 }